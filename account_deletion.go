@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/activitypub"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+)
+
+const (
+	// accountDeletionGracePeriod is how long a deleted account can still be
+	// recovered by logging in
+	accountDeletionGracePeriod = 14 * 24 * time.Hour
+	accountPurgeBatchSize      = 20
+)
+
+// errAccountDeleted is returned when logging in to an account whose deletion
+// has already been federated, too late to cancel it
+var errAccountDeleted = errors.New("account has been deleted")
+
+// purgeDeletedAccounts deletes accounts whose grace period is over. First
+// their remote followers are told the actor is gone; the account is deleted
+// once those deliveries are done. Chirps, follows and everything else the
+// user owns go with them through ON DELETE CASCADE.
+func (cfg *apiConfig) purgeDeletedAccounts(ctx context.Context) error {
+	if err := cfg.federateDeletedActors(ctx); err != nil {
+		return err
+	}
+
+	userIDs, err := cfg.db.PurgeDueUsers(ctx, accountPurgeBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		log.Printf("Purged deleted account %s", userID)
+	}
+	return nil
+}
+
+// federateDeletedActors queues a Delete of the actor to the remote
+// followers of each account that's due for deletion. Claiming the accounts
+// and queueing their deliveries happen together, so each Delete is sent once.
+func (cfg *apiConfig) federateDeletedActors(ctx context.Context) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	userIDs, err := qtx.ClaimDueActorDeletions(ctx, accountPurgeBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		inboxes, err := qtx.GetRemoteFollowerInboxes(ctx, userID)
+		if err != nil {
+			return err
+		}
+		actorURL := cfg.actorURL(userID)
+		activity, err := activitypub.NewActivity(actorURL+"#delete", "Delete", actorURL, actorURL)
+		if err != nil {
+			return err
+		}
+		activity.To = []string{activitypub.Public}
+		if err := enqueueActivity(ctx, qtx, userID, activity, inboxes); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// cancelAccountDeletion keeps an account that was scheduled for deletion.
+// Logging in is how the owner changes their mind.
+func (cfg *apiConfig) cancelAccountDeletion(ctx context.Context, user database.User) error {
+	if !user.DeletionScheduledAt.Valid {
+		return nil
+	}
+	cancelled, err := cfg.db.CancelUserDeletion(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if cancelled == 0 {
+		// Past the grace period the deletion may already have been federated
+		if !user.DeletionScheduledAt.Time.After(time.Now()) {
+			return errAccountDeleted
+		}
+		return nil
+	}
+
+	body := "You logged in to your Chirpy account, so it will no longer be deleted.\n\n" +
+		"If this wasn't you, change your password right away.\n"
+	if err := cfg.enqueueEmail(ctx, user.Email, "Your Chirpy account will not be deleted", body); err != nil {
+		log.Printf("Couldn't queue account deletion cancelled email: %s", err)
+	}
+	return nil
+}
+
+// notifyAccountDeletionScheduled tells the owner when their account will be
+// deleted and how to stop it
+func (cfg *apiConfig) notifyAccountDeletionScheduled(ctx context.Context, user database.User) {
+	body := fmt.Sprintf(
+		"Your Chirpy account is scheduled for deletion on %s, and you've been logged out everywhere.\n\n"+
+			"To keep your account, log in before then. After that date your account, chirps and "+
+			"followers are deleted for good.\n",
+		user.DeletionScheduledAt.Time.Format(time.RFC1123),
+	)
+	if err := cfg.enqueueEmail(ctx, user.Email, "Your Chirpy account will be deleted", body); err != nil {
+		log.Printf("Couldn't queue account deletion email: %s", err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+
+	"github.com/google/uuid"
+)
+
+// Scopes that can be granted to personal access tokens
+const (
+	scopeChirpsRead    = "chirps:read"
+	scopeChirpsWrite   = "chirps:write"
+	scopeMessagesRead  = "messages:read"
+	scopeMessagesWrite = "messages:write"
+	scopeProfileRead   = "profile:read"
+	scopeProfileWrite  = "profile:write"
+	scopeWebhooks      = "webhooks:manage"
+	scopeBlocksRead    = "blocks:read"
+	scopeBlocksWrite   = "blocks:write"
+)
+
+var validScopes = []string{
+	scopeChirpsRead,
+	scopeChirpsWrite,
+	scopeMessagesRead,
+	scopeMessagesWrite,
+	scopeProfileRead,
+	scopeProfileWrite,
+	scopeWebhooks,
+	scopeBlocksRead,
+	scopeBlocksWrite,
+}
+
+// scopeSession is required by routes that only a logged-in user may call,
+// such as managing tokens or two-factor authentication
+const scopeSession = ""
+
+type authInfo struct {
+	userID uuid.UUID
+	scopes []string // nil for a full user session
+}
+
+func (a authInfo) hasScope(scope string) bool {
+	if a.scopes == nil {
+		return true
+	}
+	if scope == scopeSession {
+		return false
+	}
+	return slices.Contains(a.scopes, scope)
+}
+
+// authenticateRequest resolves the bearer token to a user. It accepts access
+// tokens (JWTs), including scoped ones issued to OAuth clients, and personal
+// access tokens.
+func (cfg *apiConfig) authenticateRequest(r *http.Request) (authInfo, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return authInfo{}, err
+	}
+
+	if !auth.IsPersonalAccessToken(token) {
+		access, err := auth.ParseAccessToken(token, cfg.jwtSecret)
+		if err != nil {
+			return authInfo{}, err
+		}
+		// Access tokens aren't checked against the database. Scheduling an
+		// account for deletion revokes its refresh tokens, so any access
+		// tokens still out there stop working within the hour they're valid.
+		scopes := access.Scopes
+		if access.ClientID != uuid.Nil && scopes == nil {
+			scopes = []string{}
+		}
+		return authInfo{userID: access.UserID, scopes: scopes}, nil
+	}
+
+	pat, err := cfg.db.GetPersonalAccessTokenByHash(r.Context(), auth.HashToken(token))
+	if err != nil {
+		return authInfo{}, errors.New("invalid personal access token")
+	}
+	if err := cfg.db.TouchPersonalAccessToken(r.Context(), pat.ID); err != nil {
+		log.Printf("Couldn't update token last use: %s", err)
+	}
+	scopes := pat.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return authInfo{userID: pat.UserID, scopes: scopes}, nil
+}
+
+// authenticate resolves the caller and checks that their token grants scope,
+// responding with an error and returning false if not
+func (cfg *apiConfig) authenticate(w http.ResponseWriter, r *http.Request, scope string) (uuid.UUID, bool) {
+	info, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return uuid.Nil, false
+	}
+	if !info.hasScope(scope) {
+		respondWithError(w, http.StatusForbidden, "Token doesn't have the required scope", nil)
+		return uuid.Nil, false
+	}
+	return info.userID, true
+}
+
+// authenticateAdmin checks the ADMIN_API_KEY sent as "Authorization: ApiKey
+// <key>", responding with an error and returning false if it doesn't match.
+// Admin endpoints are disabled when no key is configured.
+func (cfg *apiConfig) authenticateAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if cfg.adminAPIKey == "" {
+		respondWithError(w, http.StatusForbidden, "Admin API is disabled", nil)
+		return false
+	}
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find API key", err)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(cfg.adminAPIKey)) != 1 {
+		respondWithError(w, http.StatusUnauthorized, "Invalid API key", nil)
+		return false
+	}
+	return true
+}
+
+// viewerID identifies the caller on public read routes, returning uuid.Nil
+// for anonymous requests and tokens that can't read chirps
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	info, err := cfg.authenticateRequest(r)
+	if err != nil || !info.hasScope(scopeChirpsRead) {
+		return uuid.Nil
+	}
+	return info.userID
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type Chirp struct {
+	ID          uuid.UUID     `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Body        string        `json:"body"`
+	UserID      uuid.UUID     `json:"user_id"`
+	Visibility  string        `json:"visibility"`
+	Mentions    []uuid.UUID   `json:"mentions,omitempty"`
+	Entities    ChirpEntities `json:"entities"`
+	Card        *LinkPreview  `json:"card,omitempty"`
+	RemoteLikes int64         `json:"remote_likes,omitempty"`
+	Pinned      bool          `json:"pinned,omitempty"`
+	Bookmarked  bool          `json:"bookmarked,omitempty"`
+	Poll        *Poll         `json:"poll,omitempty"`
+}
+
+type PollOption struct {
+	ID    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Votes *int64    `json:"votes,omitempty"`
+}
+
+// Poll tallies are only included once the viewer has voted or the poll has
+// closed, unless the author chose to show them all along
+type Poll struct {
+	ID             uuid.UUID    `json:"id"`
+	ClosesAt       time.Time    `json:"closes_at"`
+	Closed         bool         `json:"closed"`
+	ResultsVisible bool         `json:"results_visible"`
+	TotalVotes     *int64       `json:"total_votes,omitempty"`
+	VotedOptionID  *uuid.UUID   `json:"voted_option_id"`
+	Options        []PollOption `json:"options"`
+}
+
+// chirpResponse converts a single chirp; see chirpResponses
+func (cfg *apiConfig) chirpResponse(ctx context.Context, viewerID uuid.UUID, chirp database.Chirp) (Chirp, error) {
+	results, err := cfg.chirpResponses(ctx, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		return Chirp{}, err
+	}
+	return results[0], nil
+}
+
+// chirpResponses converts chirps as seen by the viewer (uuid.Nil when not
+// logged in), loading everything attached to them in batches. The chirps
+// must already be filtered down to ones the viewer may see.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]Chirp, error) {
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	polls, err := cfg.pollResponses(ctx, viewerID, chirps)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := map[uuid.UUID][]uuid.UUID{}
+	if len(chirpIDs) > 0 {
+		rows, err := cfg.db.GetChirpMentions(ctx, chirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			mentions[row.ChirpID] = append(mentions[row.ChirpID], row.UserID)
+		}
+	}
+
+	entities, cards, err := cfg.linkResponses(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteLikes := map[uuid.UUID]int64{}
+	if len(chirpIDs) > 0 {
+		rows, err := cfg.db.GetRemoteLikeCounts(ctx, chirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			remoteLikes[row.ChirpID] = row.Likes
+		}
+	}
+
+	// Bookmarks are private, so only the viewer's own are flagged
+	bookmarked := map[uuid.UUID]bool{}
+	if viewerID != uuid.Nil && len(chirpIDs) > 0 {
+		ids, err := cfg.db.GetBookmarkedChirpIDs(ctx, database.GetBookmarkedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: chirpIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			bookmarked[id] = true
+		}
+	}
+
+	results := make([]Chirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		resp := Chirp{
+			ID:          chirp.ID,
+			CreatedAt:   chirp.CreatedAt,
+			UpdatedAt:   chirp.UpdatedAt,
+			Body:        chirp.Body,
+			UserID:      chirp.UserID,
+			Visibility:  chirp.Visibility,
+			Mentions:    mentions[chirp.ID],
+			Entities:    ChirpEntities{URLs: entities[chirp.ID]},
+			Bookmarked:  bookmarked[chirp.ID],
+			RemoteLikes: remoteLikes[chirp.ID],
+		}
+		if resp.Entities.URLs == nil {
+			resp.Entities.URLs = []URLEntity{}
+		}
+		if card, ok := cards[chirp.ID]; ok {
+			resp.Card = &card
+		}
+		if poll, ok := polls[chirp.ID]; ok {
+			resp.Poll = &poll
+		}
+		results = append(results, resp)
+	}
+	return results, nil
+}
+
+// pollResponses returns the polls attached to the chirps, keyed by chirp ID
+func (cfg *apiConfig) pollResponses(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) (map[uuid.UUID]Poll, error) {
+	results := map[uuid.UUID]Poll{}
+	if len(chirps) == 0 {
+		return results, nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	authors := make(map[uuid.UUID]uuid.UUID, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+		authors[chirp.ID] = chirp.UserID
+	}
+
+	polls, err := cfg.db.GetPollsByChirps(ctx, chirpIDs)
+	if err != nil || len(polls) == 0 {
+		return results, err
+	}
+	pollIDs := make([]uuid.UUID, 0, len(polls))
+	for _, poll := range polls {
+		pollIDs = append(pollIDs, poll.ID)
+	}
+
+	tallies, err := cfg.db.GetPollOptionTallies(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	options := map[uuid.UUID][]database.GetPollOptionTalliesRow{}
+	for _, tally := range tallies {
+		options[tally.PollID] = append(options[tally.PollID], tally)
+	}
+
+	votes := map[uuid.UUID]uuid.UUID{}
+	if viewerID != uuid.Nil {
+		userVotes, err := cfg.db.GetUserPollVotes(ctx, database.GetUserPollVotesParams{
+			UserID:  viewerID,
+			PollIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range userVotes {
+			votes[vote.PollID] = vote.OptionID
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, poll := range polls {
+		resp := Poll{
+			ID:       poll.ID,
+			ClosesAt: poll.ClosesAt,
+			Closed:   !now.Before(poll.ClosesAt),
+			Options:  []PollOption{},
+		}
+		votedOptionID, voted := votes[poll.ID]
+		if voted {
+			resp.VotedOptionID = &votedOptionID
+		}
+		// Hidden results are hidden from voters, not from the poll's author
+		isAuthor := viewerID != uuid.Nil && authors[poll.ChirpID] == viewerID
+		resp.ResultsVisible = !poll.HideResults || voted || resp.Closed || isAuthor
+
+		var total int64
+		for _, option := range options[poll.ID] {
+			opt := PollOption{ID: option.ID, Text: option.Text}
+			if resp.ResultsVisible {
+				count := option.Votes
+				opt.Votes = &count
+			}
+			total += option.Votes
+			resp.Options = append(resp.Options, opt)
+		}
+		if resp.ResultsVisible {
+			resp.TotalVotes = &total
+		}
+		results[poll.ChirpID] = resp
+	}
+	return results, nil
+}
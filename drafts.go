@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/entitlements"
+
+	"github.com/google/uuid"
+)
+
+const scheduledChirpsBatchSize = 50
+
+// publishScheduledChirps publishes drafts whose publish_at has passed. Due
+// drafts are locked with SKIP LOCKED and deleted in the same transaction that
+// creates their chirps, so each is published exactly once even with several
+// instances running the job. Drafts of accounts pending deletion stay put:
+// they publish once the deletion is cancelled, and are purged with the
+// account otherwise.
+func (cfg *apiConfig) publishScheduledChirps(ctx context.Context) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	drafts, err := qtx.ClaimDueDrafts(ctx, scheduledChirpsBatchSize)
+	if err != nil {
+		return err
+	}
+
+	published := []database.Chirp{}
+	for _, draft := range drafts {
+		// The author's plan may have changed since scheduling, or they may
+		// not have verified their email. Rather than drop the chirp, turn it
+		// back into an unscheduled draft.
+		author, err := cfg.db.GetUser(ctx, draft.UserID)
+		if err != nil {
+			return err
+		}
+		caps := cfg.entitlements.For(entitlements.PlanFor(author.IsChirpyRed))
+		unverified := cfg.requireVerifiedEmail && !author.EmailVerifiedAt.Valid
+		cleaned, err := validateChirp(draft.Body, caps.MaxChirpLength)
+		if err != nil || !caps.ScheduledChirps || unverified {
+			log.Printf("Unscheduling draft %s: no longer allowed for the author", draft.ID)
+			_, err := qtx.UpdateDraft(ctx, database.UpdateDraftParams{
+				ID:         draft.ID,
+				UserID:     draft.UserID,
+				Body:       draft.Body,
+				PublishAt:  sql.NullTime{},
+				Visibility: draft.Visibility,
+				Mentions:   draft.Mentions,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := qtx.DeleteDraft(ctx, database.DeleteDraftParams{
+			ID:     draft.ID,
+			UserID: draft.UserID,
+		}); err != nil {
+			return err
+		}
+		chirp, err := cfg.createChirp(ctx, qtx, draft.UserID, cleaned, chirpAudience{
+			Visibility: draft.Visibility,
+			Mentions:   draft.Mentions,
+		})
+		if err != nil {
+			return err
+		}
+		published = append(published, chirp)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	resps, err := cfg.chirpResponses(ctx, uuid.Nil, published)
+	if err != nil {
+		return err
+	}
+	for _, resp := range resps {
+		cfg.emitEvent(ctx, resp.UserID, eventChirpCreated, resp)
+	}
+	return nil
+}
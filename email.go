@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/mailer"
+)
+
+const (
+	emailMaxAttempts = 8
+	emailBatchSize   = 20
+)
+
+func (cfg *apiConfig) enqueueEmail(ctx context.Context, to, subject, body string) error {
+	_, err := cfg.db.EnqueueEmail(ctx, database.EnqueueEmailParams{
+		ToAddress: to,
+		Subject:   subject,
+		Body:      body,
+	})
+	return err
+}
+
+// deliverPendingEmails drains the email outbox through the configured mailer.
+// Rows are claimed with SKIP LOCKED so several instances can run it at once.
+func (cfg *apiConfig) deliverPendingEmails(ctx context.Context) error {
+	emails, err := cfg.db.ClaimPendingEmails(ctx, emailBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		err := cfg.mailer.Send(ctx, mailer.Message{
+			To:      email.ToAddress,
+			Subject: email.Subject,
+			Body:    email.Body,
+		})
+		if err != nil {
+			log.Printf("Couldn't send email %s: %s", email.ID, err)
+			backoff := time.Duration(email.Attempts*email.Attempts) * time.Minute
+			status := "pending"
+			if email.Attempts >= emailMaxAttempts {
+				status = "failed"
+			}
+			err = cfg.db.MarkEmailFailed(ctx, database.MarkEmailFailedParams{
+				ID:            email.ID,
+				Status:        status,
+				LastError:     sql.NullString{String: err.Error(), Valid: true},
+				NextAttemptAt: time.Now().UTC().Add(backoff),
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := cfg.db.MarkEmailSent(ctx, email.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verificationReason is why an address is being verified, which decides how
+// the email opens
+type verificationReason int
+
+const (
+	verifySignup verificationReason = iota
+	verifyEmailChange
+)
+
+// sendVerificationEmail issues a single-use verification token for the user's
+// current email address and queues the link for delivery
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User, reason verificationReason) error {
+	token, err := auth.MakeSignedToken(cfg.jwtSecret, "verify-email")
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(24 * time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := cfg.baseURL + "/api/verify-email?token=" + url.QueryEscape(token)
+	greeting := "Welcome to Chirpy!"
+	if reason == verifyEmailChange {
+		greeting = "The email address on your Chirpy account was changed to this one."
+	}
+	body := fmt.Sprintf(
+		"%s\n\nPlease confirm your email address by opening this link within 24 hours:\n\n%s\n",
+		greeting,
+		link,
+	)
+	return cfg.enqueueEmail(ctx, user.Email, "Confirm your Chirpy email address", body)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"workspace/github.com/kozykoding/chirpy/internal/entitlements"
+
+	"github.com/google/uuid"
+)
+
+// capabilitiesFor returns what the user's plan unlocks
+func (cfg *apiConfig) capabilitiesFor(ctx context.Context, userID uuid.UUID) (entitlements.Capabilities, error) {
+	user, err := cfg.db.GetUser(ctx, userID)
+	if err != nil {
+		return entitlements.Capabilities{}, err
+	}
+	return cfg.entitlements.For(entitlements.PlanFor(user.IsChirpyRed)), nil
+}
+
+// requireFeature checks that the user's plan includes feature, responding
+// with an error and returning false if not
+func (cfg *apiConfig) requireFeature(w http.ResponseWriter, r *http.Request, userID uuid.UUID, feature entitlements.Feature) (entitlements.Capabilities, bool) {
+	caps, err := cfg.capabilitiesFor(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check your plan", err)
+		return entitlements.Capabilities{}, false
+	}
+	if !caps.Has(feature) {
+		name := strings.ReplaceAll(string(feature), "_", " ")
+		respondWithError(w, http.StatusForbidden, "Your plan doesn't include "+name, nil)
+		return entitlements.Capabilities{}, false
+	}
+	return caps, true
+}
+
+func (cfg *apiConfig) handlerEntitlementsGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeProfileRead)
+	if !ok {
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	plan := entitlements.PlanFor(user.IsChirpyRed)
+	respondWithJSON(w, http.StatusOK, struct {
+		Plan         entitlements.Plan         `json:"plan"`
+		Capabilities entitlements.Capabilities `json:"capabilities"`
+	}{
+		Plan:         plan,
+		Capabilities: cfg.entitlements.For(plan),
+	})
+}
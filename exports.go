@@ -0,0 +1,375 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+const (
+	dataExportBatchSize   = 2
+	dataExportMaxAttempts = 3
+	// dataExportRetention is how long a finished archive can be downloaded
+	dataExportRetention = 7 * 24 * time.Hour
+)
+
+// Session is the metadata of a refresh token. The token itself is never
+// exported.
+type Session struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	ClientID  *uuid.UUID `json:"oauth_client_id,omitempty"`
+}
+
+// buildDataExports builds pending account archives. Exports are claimed
+// with SKIP LOCKED like the other queues, so several instances can run it.
+func (cfg *apiConfig) buildDataExports(ctx context.Context) error {
+	exports, err := cfg.db.ClaimDataExports(ctx, dataExportBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		archive, buildErr := cfg.buildArchive(ctx, export.UserID)
+		if buildErr == nil {
+			expiresAt := time.Now().UTC().Add(dataExportRetention)
+			err := cfg.db.MarkDataExportReady(ctx, database.MarkDataExportReadyParams{
+				Archive:   archive,
+				SizeBytes: sql.NullInt64{Int64: int64(len(archive)), Valid: true},
+				ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+				ID:        export.ID,
+			})
+			if err != nil {
+				return err
+			}
+			cfg.notifyDataExportReady(ctx, export.UserID, expiresAt)
+			continue
+		}
+
+		log.Printf("Couldn't build data export %s: %s", export.ID, buildErr)
+		params := database.MarkDataExportFailedParams{
+			Status:        "pending",
+			LastError:     sql.NullString{String: buildErr.Error(), Valid: true},
+			NextAttemptAt: time.Now().UTC().Add(webhook.Backoff(int(export.Attempts))),
+			ID:            export.ID,
+		}
+		if export.Attempts >= dataExportMaxAttempts {
+			// Keep the failure around for a while so the user can see it
+			params.Status = "failed"
+			params.ExpiresAt = sql.NullTime{Time: time.Now().UTC().Add(dataExportRetention), Valid: true}
+		}
+		if err := cfg.db.MarkDataExportFailed(ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildArchive collects everything the account owns into a ZIP of JSON
+// files. Local users can't like chirps, so likes.json holds the likes their
+// chirps got from other servers. Chirpy has no media uploads to export.
+func (cfg *apiConfig) buildArchive(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	user, err := cfg.db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The owner can see all of their own chirps, whatever the visibility
+	chirps, err := cfg.db.GetChirpsByAuthor(ctx, database.GetChirpsByAuthorParams{
+		UserID:   userID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	chirpResults, err := cfg.chirpResponses(ctx, userID, chirps)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarked, err := cfg.db.ListBookmarkedChirps(ctx, database.ListBookmarkedChirpsParams{
+		UserID: userID,
+		Limit:  math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+	bookmarkResults, err := cfg.chirpResponses(ctx, userID, bookmarked)
+	if err != nil {
+		return nil, err
+	}
+
+	drafts, err := cfg.db.ListDrafts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	draftResults := []Draft{}
+	for _, draft := range drafts {
+		draftResults = append(draftResults, draftResponse(draft))
+	}
+
+	following, err := cfg.db.ListFollowing(ctx, database.ListFollowingParams{
+		FollowerID: userID,
+		Limit:      math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+	followers, err := cfg.db.ListFollowers(ctx, database.ListFollowersParams{
+		FolloweeID: userID,
+		Limit:      math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+	remoteFollowing, err := cfg.db.ListRemoteFollowing(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	type follows struct {
+		Following       []Follow       `json:"following"`
+		Followers       []Follow       `json:"followers"`
+		RemoteFollowing []RemoteFollow `json:"remote_following"`
+	}
+	followResults := follows{Following: []Follow{}, Followers: []Follow{}, RemoteFollowing: []RemoteFollow{}}
+	for _, follow := range following {
+		followResults.Following = append(followResults.Following, Follow{UserID: follow.FolloweeID, CreatedAt: follow.CreatedAt})
+	}
+	for _, follow := range followers {
+		followResults.Followers = append(followResults.Followers, Follow{UserID: follow.FollowerID, CreatedAt: follow.CreatedAt})
+	}
+	for _, follow := range remoteFollowing {
+		followResults.RemoteFollowing = append(followResults.RemoteFollowing, RemoteFollow{
+			ActorID:   follow.RemoteActorID,
+			ActorURL:  follow.ActorUrl,
+			Username:  follow.Username,
+			Accepted:  follow.AcceptedAt.Valid,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+
+	refreshTokens, err := cfg.db.ListRefreshTokenSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := cfg.db.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	type sessions struct {
+		Sessions             []Session             `json:"sessions"`
+		PersonalAccessTokens []PersonalAccessToken `json:"personal_access_tokens"`
+	}
+	sessionResults := sessions{Sessions: []Session{}, PersonalAccessTokens: []PersonalAccessToken{}}
+	for _, token := range refreshTokens {
+		session := Session{CreatedAt: token.CreatedAt, ExpiresAt: token.ExpiresAt}
+		if token.RevokedAt.Valid {
+			session.RevokedAt = &token.RevokedAt.Time
+		}
+		if token.ClientID.Valid {
+			session.ClientID = &token.ClientID.UUID
+		}
+		sessionResults.Sessions = append(sessionResults.Sessions, session)
+	}
+	for _, token := range tokens {
+		sessionResults.PersonalAccessTokens = append(sessionResults.PersonalAccessTokens, personalAccessTokenResponse(token))
+	}
+
+	conversations, err := cfg.db.ListConversationsForUser(ctx, database.ListConversationsForUserParams{
+		UserID: userID,
+		Limit:  math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+	conversationResults, err := cfg.conversationResponses(ctx, userID, conversations)
+	if err != nil {
+		return nil, err
+	}
+	type conversationExport struct {
+		Conversation
+		Messages []Message `json:"messages"`
+	}
+	messageResults := []conversationExport{}
+	for _, conversation := range conversationResults {
+		// Oldest first; messages from blocked users stay hidden, as in the app
+		messages, err := cfg.db.ListLatestMessages(ctx, database.ListLatestMessagesParams{
+			ConversationID: conversation.ID,
+			ViewerID:       userID,
+			MaxResults:     math.MaxInt32,
+		})
+		if err != nil {
+			return nil, err
+		}
+		export := conversationExport{Conversation: conversation, Messages: []Message{}}
+		for _, message := range slices.Backward(messages) {
+			export.Messages = append(export.Messages, messageResponse(message))
+		}
+		messageResults = append(messageResults, export)
+	}
+
+	ownedLists, err := cfg.db.ListListsByOwner(ctx, database.ListListsByOwnerParams{
+		UserID:         userID,
+		IncludePrivate: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	followedLists, err := cfg.db.ListFollowedLists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	type listExport struct {
+		List
+		Members []ListMember `json:"members"`
+	}
+	type lists struct {
+		Lists    []listExport `json:"lists"`
+		Followed []List       `json:"followed"`
+	}
+	listResults := lists{Lists: []listExport{}, Followed: listResponses(followedLists)}
+	for _, list := range ownedLists {
+		members, err := cfg.db.ListListMembers(ctx, list.ID)
+		if err != nil {
+			return nil, err
+		}
+		export := listExport{List: listResponse(list), Members: []ListMember{}}
+		for _, member := range members {
+			export.Members = append(export.Members, ListMember{UserID: member.UserID, CreatedAt: member.CreatedAt})
+		}
+		listResults.Lists = append(listResults.Lists, export)
+	}
+
+	blocks, err := cfg.db.ListBlockedUsers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	blockResults := []UserBlock{}
+	for _, block := range blocks {
+		blockResults = append(blockResults, UserBlock{UserID: block.BlockedID, CreatedAt: block.CreatedAt})
+	}
+
+	votes, err := cfg.db.ListPollVotesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	type pollVote struct {
+		PollID    uuid.UUID `json:"poll_id"`
+		OptionID  uuid.UUID `json:"option_id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	voteResults := []pollVote{}
+	for _, vote := range votes {
+		voteResults = append(voteResults, pollVote{PollID: vote.PollID, OptionID: vote.OptionID, CreatedAt: vote.CreatedAt})
+	}
+
+	likes, err := cfg.db.ListRemoteLikesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	type remoteLike struct {
+		ChirpID   uuid.UUID `json:"chirp_id"`
+		ActorURL  string    `json:"actor_url"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	likeResults := []remoteLike{}
+	for _, like := range likes {
+		likeResults = append(likeResults, remoteLike{ChirpID: like.ChirpID, ActorURL: like.ActorUrl, CreatedAt: like.CreatedAt})
+	}
+
+	// Endpoint secrets are left out, like personal access tokens
+	endpoints, err := cfg.db.ListWebhookEndpoints(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	endpointResults := []WebhookEndpoint{}
+	for _, endpoint := range endpoints {
+		endpointResults = append(endpointResults, webhookEndpointResponse(endpoint))
+	}
+
+	type profile struct {
+		User
+		PinnedChirpID *uuid.UUID `json:"pinned_chirp_id"`
+	}
+	profileResult := profile{User: User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		IsChirpyRed:   user.IsChirpyRed,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		IsPrivate:     user.IsPrivate,
+	}}
+	if user.PinnedChirpID.Valid {
+		profileResult.PinnedChirpID = &user.PinnedChirpID.UUID
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profileResult},
+		{"chirps.json", chirpResults},
+		{"drafts.json", draftResults},
+		{"bookmarks.json", bookmarkResults},
+		{"likes.json", likeResults},
+		{"poll_votes.json", voteResults},
+		{"follows.json", followResults},
+		{"lists.json", listResults},
+		{"blocks.json", blockResults},
+		{"messages.json", messageResults},
+		{"sessions.json", sessionResults},
+		{"webhooks.json", endpointResults},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// notifyDataExportReady tells the user their archive can be downloaded. The
+// email doesn't carry the link: it's fetched after logging in, so a
+// forwarded email doesn't give the archive away.
+func (cfg *apiConfig) notifyDataExportReady(ctx context.Context, userID uuid.UUID, expiresAt time.Time) {
+	user, err := cfg.db.GetUser(ctx, userID)
+	if err != nil {
+		log.Printf("Couldn't notify user %s of their data export: %s", userID, err)
+		return
+	}
+	body := fmt.Sprintf(
+		"The archive of your Chirpy account is ready.\n\n"+
+			"Log in and call GET /api/users/me/export for a download link. "+
+			"The archive is deleted after %s.\n",
+		expiresAt.Format(time.RFC1123),
+	)
+	if err := cfg.enqueueEmail(ctx, user.Email, "Your Chirpy data export is ready", body); err != nil {
+		log.Printf("Couldn't queue data export email: %s", err)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/activitypub"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+const (
+	activityDeliveryBatchSize   = 20
+	activityDeliveryMaxAttempts = 8
+)
+
+// actorURL is where a user is served as an ActivityPub actor. Users don't
+// have handles, so their WebFinger account is their ID: acct:{userID}@{host}.
+func (cfg *apiConfig) actorURL(userID uuid.UUID) string {
+	return cfg.baseURL + "/ap/users/" + userID.String()
+}
+
+func (cfg *apiConfig) noteURL(chirpID uuid.UUID) string {
+	return cfg.baseURL + "/ap/chirps/" + chirpID.String()
+}
+
+// newActivityID names an activity that's only ever delivered, never served
+func (cfg *apiConfig) newActivityID() string {
+	return cfg.baseURL + "/ap/activities/" + uuid.New().String()
+}
+
+// federationHost is the domain in local WebFinger accounts
+func (cfg *apiConfig) federationHost() string {
+	u, err := url.Parse(cfg.baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// localID returns the ID in a local actor or note URL with the given prefix
+func (cfg *apiConfig) localID(rawURL, prefix string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(rawURL, cfg.baseURL+prefix)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(rest)
+	return id, err == nil
+}
+
+// actorKey returns the user's key pair, creating it the first time
+func (cfg *apiConfig) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := cfg.db.GetActorKey(ctx, userID)
+	if err != sql.ErrNoRows {
+		return key, err
+	}
+
+	publicPEM, privatePEM, err := activitypub.GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	// Two requests racing to create it both end up with whichever won
+	err = cfg.db.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	})
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	return cfg.db.GetActorKey(ctx, userID)
+}
+
+func (cfg *apiConfig) actorSigner(ctx context.Context, userID uuid.UUID) (activitypub.Signer, error) {
+	key, err := cfg.actorKey(ctx, userID)
+	if err != nil {
+		return activitypub.Signer{}, err
+	}
+	privateKey, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return activitypub.Signer{}, err
+	}
+	return activitypub.Signer{KeyID: cfg.actorURL(userID) + "#main-key", Key: privateKey}, nil
+}
+
+// chirpNote renders a public chirp as a Note
+func (cfg *apiConfig) chirpNote(chirp database.Chirp) activitypub.Note {
+	note := activitypub.Note{
+		ID:           cfg.noteURL(chirp.ID),
+		Type:         "Note",
+		AttributedTo: cfg.actorURL(chirp.UserID),
+		Content:      activitypub.NoteContent(chirp.Body),
+		URL:          cfg.baseURL + "/api/chirps/" + chirp.ID.String(),
+		Published:    chirp.CreatedAt.UTC().Format(time.RFC3339),
+		To:           []string{activitypub.Public},
+		Cc:           []string{cfg.actorURL(chirp.UserID) + "/followers"},
+	}
+	if chirp.UpdatedAt.After(chirp.CreatedAt) {
+		note.Updated = chirp.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return note
+}
+
+// chirpCreateActivity wraps a Note the way outboxes and deliveries carry it
+func (cfg *apiConfig) chirpCreateActivity(chirp database.Chirp) (activitypub.Activity, error) {
+	note := cfg.chirpNote(chirp)
+	activity, err := activitypub.NewActivity(note.ID+"/activity", "Create", note.AttributedTo, note)
+	if err != nil {
+		return activitypub.Activity{}, err
+	}
+	activity.Published = note.Published
+	activity.To = note.To
+	activity.Cc = note.Cc
+	return activity, nil
+}
+
+// enqueueActivity queues a delivery of the activity to each inbox, signed
+// as the user
+func enqueueActivity(ctx context.Context, q *database.Queries, userID uuid.UUID, activity activitypub.Activity, inboxes []string) error {
+	if len(inboxes) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return q.CreateActivityDeliveries(ctx, database.CreateActivityDeliveriesParams{
+		UserID:    userID,
+		InboxUrls: inboxes,
+		Payload:   payload,
+	})
+}
+
+// isPublicChirp reports whether anyone, and so remote servers, can see the
+// chirp
+func isPublicChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID) (bool, error) {
+	_, err := q.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: uuid.Nil,
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// federateChirp queues a new chirp for the author's remote followers. Only
+// chirps anyone can see are federated: remote servers can't enforce the
+// other visibility rules.
+func (cfg *apiConfig) federateChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	public, err := isPublicChirp(ctx, q, chirp.ID)
+	if err != nil || !public {
+		return err
+	}
+	inboxes, err := q.GetRemoteFollowerInboxes(ctx, chirp.UserID)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+	activity, err := cfg.chirpCreateActivity(chirp)
+	if err != nil {
+		return err
+	}
+	return enqueueActivity(ctx, q, chirp.UserID, activity, inboxes)
+}
+
+// federateChirpUpdated sends an edited chirp to the author's remote
+// followers, who otherwise keep showing the old text
+func (cfg *apiConfig) federateChirpUpdated(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	public, err := isPublicChirp(ctx, q, chirp.ID)
+	if err != nil || !public {
+		return err
+	}
+	inboxes, err := q.GetRemoteFollowerInboxes(ctx, chirp.UserID)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+	note := cfg.chirpNote(chirp)
+	activity, err := activitypub.NewActivity(cfg.newActivityID(), "Update", note.AttributedTo, note)
+	if err != nil {
+		return err
+	}
+	activity.Published = note.Updated
+	activity.To = note.To
+	activity.Cc = note.Cc
+	return enqueueActivity(ctx, q, chirp.UserID, activity, inboxes)
+}
+
+// federateChirpDeleted tells remote followers a federated chirp is gone.
+// Like emitEvent, failing to queue doesn't fail the caller's request.
+func (cfg *apiConfig) federateChirpDeleted(ctx context.Context, chirp database.Chirp) {
+	inboxes, err := cfg.db.GetRemoteFollowerInboxes(ctx, chirp.UserID)
+	if err != nil {
+		log.Printf("Couldn't find remote followers of %s: %s", chirp.UserID, err)
+		return
+	}
+	noteID := cfg.noteURL(chirp.ID)
+	activity, err := activitypub.NewActivity(noteID+"#delete", "Delete", cfg.actorURL(chirp.UserID), struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}{
+		ID:   noteID,
+		Type: "Tombstone",
+	})
+	if err == nil {
+		activity.To = []string{activitypub.Public}
+		err = enqueueActivity(ctx, cfg.db, chirp.UserID, activity, inboxes)
+	}
+	if err != nil {
+		log.Printf("Couldn't queue deletion of chirp %s: %s", chirp.ID, err)
+	}
+}
+
+// remoteActor returns the cached actor that owns keyID, fetching its actor
+// document when it isn't cached or refresh is set (e.g. the key rotated)
+func (cfg *apiConfig) remoteActor(ctx context.Context, keyID string, refresh bool) (database.RemoteActor, error) {
+	if !refresh {
+		actor, err := cfg.db.GetRemoteActorByKeyID(ctx, keyID)
+		if err != sql.ErrNoRows {
+			return actor, err
+		}
+	}
+
+	// Key IDs are the actor URL plus a fragment on every common server
+	actorURL, _, _ := strings.Cut(keyID, "#")
+	actor, err := cfg.federation.FetchActor(ctx, actorURL, nil)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	if actor.PublicKey.ID != keyID {
+		return database.RemoteActor{}, fmt.Errorf("key %s doesn't belong to %s", keyID, actorURL)
+	}
+	return cfg.saveRemoteActor(ctx, actor)
+}
+
+func (cfg *apiConfig) saveRemoteActor(ctx context.Context, actor activitypub.Actor) (database.RemoteActor, error) {
+	if _, ok := cfg.localID(actor.ID, "/ap/users/"); ok {
+		return database.RemoteActor{}, fmt.Errorf("%s is a local actor", actor.ID)
+	}
+	u, err := url.Parse(actor.ID)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	return cfg.db.UpsertRemoteActor(ctx, database.UpsertRemoteActorParams{
+		ActorUrl:       actor.ID,
+		InboxUrl:       actor.Inbox,
+		SharedInboxUrl: actor.SharedInbox(),
+		KeyID:          actor.PublicKey.ID,
+		PublicKeyPem:   actor.PublicKey.PublicKeyPem,
+		Username:       actor.PreferredUsername + "@" + u.Host,
+	})
+}
+
+// deliverActivities sends due activity deliveries. Like webhook deliveries,
+// rows are claimed with SKIP LOCKED so several instances can run it at once.
+func (cfg *apiConfig) deliverActivities(ctx context.Context) error {
+	deliveries, err := cfg.db.ClaimActivityDeliveries(ctx, activityDeliveryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		signer, err := cfg.actorSigner(ctx, delivery.UserID)
+		if err != nil {
+			return err
+		}
+
+		sendErr := cfg.federation.Deliver(ctx, delivery.InboxUrl, delivery.Payload, signer)
+		if sendErr == nil {
+			if err := cfg.db.MarkActivityDeliveryDelivered(ctx, delivery.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Retry with exponential backoff, except for client errors that
+		// won't go away, like an actor that no longer exists
+		status := "pending"
+		var statusErr *activitypub.StatusError
+		if delivery.Attempts >= activityDeliveryMaxAttempts ||
+			(errors.As(sendErr, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 &&
+				statusErr.StatusCode != http.StatusRequestTimeout && statusErr.StatusCode != http.StatusTooManyRequests) {
+			status = "dead"
+		}
+		err = cfg.db.MarkActivityDeliveryFailed(ctx, database.MarkActivityDeliveryFailedParams{
+			ID:            delivery.ID,
+			Status:        status,
+			LastError:     sql.NullString{String: sendErr.Error(), Valid: true},
+			NextAttemptAt: time.Now().UTC().Add(webhook.Backoff(int(delivery.Attempts))),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
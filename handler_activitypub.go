@@ -0,0 +1,519 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/activitypub"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxInboxBodyBytes = 1 << 20
+	outboxPageSize    = 20
+	// maxOutboxPage bounds how deep the outbox can be paged with OFFSET
+	maxOutboxPage = 500
+)
+
+func respondWithActivityJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(dat)
+}
+
+// handlerWebFinger resolves acct:{userID}@{host}, or an actor URL, to the
+// user's actor
+func (cfg *apiConfig) handlerWebFinger(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	if resource == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing resource", nil)
+		return
+	}
+
+	userID, ok := cfg.localID(resource, "/ap/users/")
+	if !ok {
+		account, isAccount := strings.CutPrefix(resource, "acct:")
+		name, host, _ := strings.Cut(account, "@")
+		id, err := uuid.Parse(name)
+		if !isAccount || err != nil || !strings.EqualFold(host, cfg.federationHost()) {
+			respondWithError(w, http.StatusNotFound, "Account not found", err)
+			return
+		}
+		userID = id
+	}
+
+	if _, err := cfg.db.GetUser(r.Context(), userID); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Account not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	actorURL := cfg.actorURL(userID)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", activitypub.JRDContentType)
+	dat, _ := json.Marshal(activitypub.JRD{
+		Subject: "acct:" + userID.String() + "@" + cfg.federationHost(),
+		Aliases: []string{actorURL},
+		Links: []activitypub.JRDLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorURL},
+		},
+	})
+	w.Write(dat)
+}
+
+// getFederatedUser returns the user in the path, or responds with an error
+func (cfg *apiConfig) getFederatedUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return database.User{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// handlerActorGet serves the user's actor. Unlike the outbox, it stays up
+// while the account is pending deletion: remote servers fetch its key to
+// verify the Delete sent once the grace period ends.
+func (cfg *apiConfig) handlerActorGet(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.getFederatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := cfg.actorKey(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve actor key", err)
+		return
+	}
+
+	actorURL := cfg.actorURL(user.ID)
+	respondWithActivityJSON(w, http.StatusOK, activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                actorURL,
+		Type:              "Person",
+		PreferredUsername: user.ID.String(),
+		URL:               cfg.baseURL + "/api/chirps?author_id=" + user.ID.String(),
+		Inbox:             actorURL + "/inbox",
+		Outbox:            actorURL + "/outbox",
+		Followers:         actorURL + "/followers",
+		Following:         actorURL + "/following",
+		// Remote follows of private accounts are refused; see inboxFollow
+		ManuallyApprovesFollowers: user.IsPrivate,
+		PublicKey: activitypub.PublicKey{
+			ID:           actorURL + "#main-key",
+			Owner:        actorURL,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+		Endpoints: &activitypub.Endpoints{SharedInbox: cfg.baseURL + "/ap/inbox"},
+	})
+}
+
+// handlerOutboxGet serves the user's public chirps as Create activities, a
+// page at a time. Accounts pending deletion have no outbox.
+func (cfg *apiConfig) handlerOutboxGet(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.getFederatedUser(w, r)
+	if !ok {
+		return
+	}
+	if user.DeletionScheduledAt.Valid {
+		respondWithError(w, http.StatusNotFound, "User not found", nil)
+		return
+	}
+	outboxURL := cfg.actorURL(user.ID) + "/outbox"
+
+	// 1. Without a page, serve the collection itself with a link to the
+	// first page, as Mastodon does
+	pageParam := r.URL.Query().Get("page")
+	if pageParam == "" {
+		count, err := cfg.db.CountChirpsByAuthor(r.Context(), database.CountChirpsByAuthorParams{
+			UserID:   user.ID,
+			ViewerID: uuid.Nil,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't count chirps", err)
+			return
+		}
+		respondWithActivityJSON(w, http.StatusOK, activitypub.OrderedCollection{
+			Context:    activitypub.Context,
+			ID:         outboxURL,
+			Type:       "OrderedCollection",
+			TotalItems: count,
+			First:      outboxURL + "?page=1",
+		})
+		return
+	}
+	page, err := strconv.Atoi(pageParam)
+	if err != nil || page < 1 || page > maxOutboxPage {
+		respondWithError(w, http.StatusBadRequest, "Invalid page", err)
+		return
+	}
+
+	// 2. Newest first. One extra chirp tells whether there's a next page.
+	chirps, err := cfg.db.GetLatestChirpsByAuthor(r.Context(), database.GetLatestChirpsByAuthorParams{
+		UserID:     user.ID,
+		ViewerID:   uuid.Nil,
+		MaxResults: outboxPageSize + 1,
+		PageOffset: int32((page - 1) * outboxPageSize),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+	resp := activitypub.OrderedCollectionPage{
+		Context:      activitypub.Context,
+		ID:           outboxURL + "?page=" + strconv.Itoa(page),
+		Type:         "OrderedCollectionPage",
+		PartOf:       outboxURL,
+		OrderedItems: []any{},
+	}
+	if len(chirps) > outboxPageSize {
+		chirps = chirps[:outboxPageSize]
+		resp.Next = outboxURL + "?page=" + strconv.Itoa(page+1)
+	}
+	if page > 1 {
+		resp.Prev = outboxURL + "?page=" + strconv.Itoa(page-1)
+	}
+
+	for _, chirp := range chirps {
+		activity, err := cfg.chirpCreateActivity(chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't render chirp", err)
+			return
+		}
+		activity.Context = nil
+		resp.OrderedItems = append(resp.OrderedItems, activity)
+	}
+	respondWithActivityJSON(w, http.StatusOK, resp)
+}
+
+// handlerFollowersCollectionGet only reveals how many remote followers the
+// user has, not who they are
+func (cfg *apiConfig) handlerFollowersCollectionGet(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.getFederatedUser(w, r)
+	if !ok {
+		return
+	}
+	count, err := cfg.db.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count followers", err)
+		return
+	}
+	respondWithActivityJSON(w, http.StatusOK, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURL(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: count,
+	})
+}
+
+func (cfg *apiConfig) handlerFollowingCollectionGet(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.getFederatedUser(w, r)
+	if !ok {
+		return
+	}
+	count, err := cfg.db.CountRemoteFollowing(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count followed accounts", err)
+		return
+	}
+	respondWithActivityJSON(w, http.StatusOK, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURL(user.ID) + "/following",
+		Type:       "OrderedCollection",
+		TotalItems: count,
+	})
+}
+
+// handlerNoteGet serves a chirp as a Note. Only chirps anyone can see are
+// served; the rest are a 404.
+func (cfg *apiConfig) handlerNoteGet(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	chirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: uuid.Nil,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+
+	note := cfg.chirpNote(chirp)
+	note.Context = activitypub.Context
+	respondWithActivityJSON(w, http.StatusOK, note)
+}
+
+// handlerInbox receives activities from remote servers, at both the shared
+// inbox and each user's own. Every activity must be signed by its actor.
+func (cfg *apiConfig) handlerInbox(w http.ResponseWriter, r *http.Request) {
+	// 1. Read the body, which the signature's digest covers
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBodyBytes+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read body", err)
+		return
+	}
+	if len(body) > maxInboxBodyBytes {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Activity is too large", nil)
+		return
+	}
+
+	// 2. Verify the signature, refetching the sender's key once in case it
+	// rotated since we cached it
+	sig, err := activitypub.ParseSignature(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed signature", err)
+		return
+	}
+	actor, err := cfg.remoteActor(r.Context(), sig.KeyID, false)
+	if err == nil {
+		err = cfg.verifyInboxSignature(r, body, sig, actor)
+		if errors.Is(err, activitypub.ErrInvalidSignature) {
+			actor, err = cfg.remoteActor(r.Context(), sig.KeyID, true)
+			if err == nil {
+				err = cfg.verifyInboxSignature(r, body, sig, actor)
+			}
+		}
+	}
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify signature", err)
+		return
+	}
+
+	// 3. The signer can only act as themselves
+	activity := activitypub.Activity{}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode activity", err)
+		return
+	}
+	if activity.Actor != actor.ActorUrl {
+		respondWithError(w, http.StatusForbidden, "Activity isn't from the signer", nil)
+		return
+	}
+
+	// 4. Apply it. Activities we don't handle are accepted and ignored.
+	switch activity.Type {
+	case "Follow":
+		err = cfg.inboxFollow(r, actor, activity)
+	case "Undo":
+		err = cfg.inboxUndo(r, actor, activity)
+	case "Like":
+		err = cfg.inboxLike(r, actor, activity)
+	case "Create":
+		err = cfg.inboxCreate(r, actor, activity)
+	case "Delete":
+		err = cfg.db.DeleteRemoteNote(r.Context(), database.DeleteRemoteNoteParams{
+			RemoteActorID: actor.ID,
+			ObjectID:      activitypub.ObjectID(activity.Object),
+		})
+	case "Accept":
+		_, err = cfg.db.AcceptRemoteFollowing(r.Context(), database.AcceptRemoteFollowingParams{
+			RemoteActorID: actor.ID,
+			ActivityID:    activitypub.ObjectID(activity.Object),
+		})
+	case "Reject":
+		_, err = cfg.db.RejectRemoteFollowing(r.Context(), database.RejectRemoteFollowingParams{
+			RemoteActorID: actor.ID,
+			ActivityID:    activitypub.ObjectID(activity.Object),
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process activity", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *apiConfig) verifyInboxSignature(r *http.Request, body []byte, sig activitypub.Signature, actor database.RemoteActor) error {
+	key, err := activitypub.ParsePublicKey(actor.PublicKeyPem)
+	if err != nil {
+		return err
+	}
+	return sig.Verify(r, body, key, time.Now())
+}
+
+// inboxFollow answers a follow of a local user. Public accounts accept
+// every follow; private accounts refuse them, since their chirps aren't
+// federated.
+func (cfg *apiConfig) inboxFollow(r *http.Request, actor database.RemoteActor, activity activitypub.Activity) error {
+	userID, ok := cfg.localID(activitypub.ObjectID(activity.Object), "/ap/users/")
+	if !ok {
+		return nil
+	}
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	answer := "Reject"
+	if !user.IsPrivate {
+		answer = "Accept"
+		err := qtx.CreateRemoteFollower(r.Context(), database.CreateRemoteFollowerParams{
+			RemoteActorID: actor.ID,
+			UserID:        user.ID,
+			ActivityID:    activity.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The answer embeds the follow, as Mastodon expects
+	activity.Context = nil
+	reply, err := activitypub.NewActivity(cfg.newActivityID(), answer, cfg.actorURL(user.ID), activity)
+	if err != nil {
+		return err
+	}
+	if err := enqueueActivity(r.Context(), qtx, user.ID, reply, []string{actor.InboxUrl}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// inboxUndo undoes a follow or like. The undone activity may be embedded or
+// just its ID, so both are tried.
+func (cfg *apiConfig) inboxUndo(r *http.Request, actor database.RemoteActor, activity activitypub.Activity) error {
+	undoneID := activitypub.ObjectID(activity.Object)
+	undoneType := activitypub.ObjectType(activity.Object)
+
+	if undoneType == "" || undoneType == "Follow" {
+		_, err := cfg.db.DeleteRemoteFollowerByActivity(r.Context(), database.DeleteRemoteFollowerByActivityParams{
+			RemoteActorID: actor.ID,
+			ActivityID:    undoneID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if undoneType == "" || undoneType == "Like" {
+		_, err := cfg.db.DeleteRemoteLikeByActivity(r.Context(), database.DeleteRemoteLikeByActivityParams{
+			RemoteActorID: actor.ID,
+			ActivityID:    undoneID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inboxLike records a like of a chirp that's federated
+func (cfg *apiConfig) inboxLike(r *http.Request, actor database.RemoteActor, activity activitypub.Activity) error {
+	chirpID, ok := cfg.localID(activitypub.ObjectID(activity.Object), "/ap/chirps/")
+	if !ok {
+		return nil
+	}
+	public, err := isPublicChirp(r.Context(), cfg.db, chirpID)
+	if err != nil || !public {
+		return err
+	}
+	return cfg.db.CreateRemoteLike(r.Context(), database.CreateRemoteLikeParams{
+		RemoteActorID: actor.ID,
+		ChirpID:       chirpID,
+		ActivityID:    activity.ID,
+	})
+}
+
+// inboxCreate stores a note from an actor someone here follows. Notes from
+// anyone else are dropped.
+func (cfg *apiConfig) inboxCreate(r *http.Request, actor database.RemoteActor, activity activitypub.Activity) error {
+	var note struct {
+		ID           string          `json:"id"`
+		Type         string          `json:"type"`
+		AttributedTo string          `json:"attributedTo"`
+		Content      string          `json:"content"`
+		URL          json.RawMessage `json:"url"`
+		Published    string          `json:"published"`
+	}
+	if err := json.Unmarshal(activity.Object, &note); err != nil || note.Type != "Note" {
+		return nil
+	}
+	if note.AttributedTo != actor.ActorUrl || note.ID == "" {
+		return nil
+	}
+	// An actor can only create notes on its own server, or it could claim
+	// (and later delete) notes that belong to someone else
+	if !sameOrigin(note.ID, actor.ActorUrl) {
+		return nil
+	}
+
+	followed, err := cfg.db.IsRemoteActorFollowed(r.Context(), actor.ID)
+	if err != nil || !followed {
+		return err
+	}
+
+	noteURL := activitypub.ObjectID(note.URL)
+	if noteURL == "" {
+		noteURL = note.ID
+	}
+	published, err := time.Parse(time.RFC3339, note.Published)
+	if err != nil {
+		published = time.Now()
+	}
+	return cfg.db.CreateRemoteNote(r.Context(), database.CreateRemoteNoteParams{
+		RemoteActorID: actor.ID,
+		ObjectID:      note.ID,
+		Url:           noteURL,
+		Content:       activitypub.PlainText(note.Content),
+		PublishedAt:   published.UTC(),
+	})
+}
+
+// sameOrigin reports whether two URLs have the same scheme and host
+func sameOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && strings.EqualFold(ua.Host, ub.Host)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type InboundWebhook struct {
+	ID                uuid.UUID       `json:"id"`
+	ReceivedAt        time.Time       `json:"received_at"`
+	ReplayOf          *uuid.UUID      `json:"replay_of,omitempty"`
+	Verified          bool            `json:"verified"`
+	VerificationError string          `json:"verification_error,omitempty"`
+	EventID           string          `json:"event_id,omitempty"`
+	Event             string          `json:"event,omitempty"`
+	Outcome           string          `json:"outcome"`
+	ResponseCode      int32           `json:"response_code,omitempty"`
+	Error             string          `json:"error,omitempty"`
+	ProcessedAt       *time.Time      `json:"processed_at,omitempty"`
+	Headers           json.RawMessage `json:"headers,omitempty"`
+	Body              string          `json:"body,omitempty"`
+}
+
+// inboundWebhookResponse converts a log row; headers and body are only
+// included when inspecting a single delivery
+func inboundWebhookResponse(entry database.InboundWebhook, full bool) InboundWebhook {
+	resp := InboundWebhook{
+		ID:                entry.ID,
+		ReceivedAt:        entry.ReceivedAt,
+		Verified:          entry.Verified,
+		VerificationError: entry.VerificationError.String,
+		EventID:           entry.EventID.String,
+		Event:             entry.Event.String,
+		Outcome:           entry.Outcome,
+		ResponseCode:      entry.ResponseCode.Int32,
+		Error:             entry.Error.String,
+	}
+	if entry.ReplayOf.Valid {
+		resp.ReplayOf = &entry.ReplayOf.UUID
+	}
+	if entry.ProcessedAt.Valid {
+		resp.ProcessedAt = &entry.ProcessedAt.Time
+	}
+	if full {
+		resp.Headers = entry.Headers
+		resp.Body = string(entry.Body)
+	}
+	return resp
+}
+
+func (cfg *apiConfig) handlerAdminWebhooksGet(w http.ResponseWriter, r *http.Request) {
+	if !cfg.authenticateAdmin(w, r) {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// ?outcome=failed narrows the list down to what needs attention
+	entries, err := cfg.db.ListInboundWebhooks(r.Context(), database.ListInboundWebhooksParams{
+		Outcome: r.URL.Query().Get("outcome"),
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhooks", err)
+		return
+	}
+
+	results := []InboundWebhook{}
+	for _, entry := range entries {
+		results = append(results, inboundWebhookResponse(entry, false))
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func (cfg *apiConfig) handlerAdminWebhooksGetSingle(w http.ResponseWriter, r *http.Request) {
+	if !cfg.authenticateAdmin(w, r) {
+		return
+	}
+
+	entry, ok := cfg.getInboundWebhook(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, inboundWebhookResponse(entry, true))
+}
+
+func (cfg *apiConfig) handlerAdminWebhooksReplay(w http.ResponseWriter, r *http.Request) {
+	// 1. Authenticate
+	if !cfg.authenticateAdmin(w, r) {
+		return
+	}
+
+	// 2. Look up the original delivery
+	entry, ok := cfg.getInboundWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	// 3. Only deliveries that really came from Polka may be replayed. The
+	// signature isn't checked again: its timestamp is long out of tolerance.
+	if !entry.Verified {
+		respondWithError(w, http.StatusConflict, "Only verified webhooks can be replayed", nil)
+		return
+	}
+
+	// 4. Run it through the same processing as a live delivery, logged as a
+	// new entry pointing back at the original
+	headers := http.Header{}
+	if err := json.Unmarshal(entry.Headers, &headers); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode stored headers", err)
+		return
+	}
+	replayID := cfg.logInboundWebhook(r.Context(), headers, entry.Body, uuid.NullUUID{UUID: entry.ID, Valid: true})
+	res := cfg.processPolkaWebhook(r.Context(), entry.Body)
+	cfg.finishInboundWebhook(r.Context(), replayID, true, nil, res)
+
+	if replayID == uuid.Nil {
+		respondWithError(w, http.StatusInternalServerError, "Replayed, but couldn't log the replay", nil)
+		return
+	}
+	replay, err := cfg.db.GetInboundWebhook(r.Context(), replayID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve replay", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, inboundWebhookResponse(replay, false))
+}
+
+func (cfg *apiConfig) getInboundWebhook(w http.ResponseWriter, r *http.Request) (database.InboundWebhook, bool) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID", err)
+		return database.InboundWebhook{}, false
+	}
+
+	entry, err := cfg.db.GetInboundWebhook(r.Context(), webhookID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Webhook not found", err)
+			return database.InboundWebhook{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhook", err)
+		return database.InboundWebhook{}, false
+	}
+	return entry, true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type UserBlock struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handlerBlocksCreate blocks a user. Blocked users can't start or continue a
+// direct conversation with the blocker, and their messages are hidden from
+// the blocker in group conversations. Blocking also ends any follows and
+// follow requests between the two, so neither keeps seeing the other's
+// followers-only chirps.
+func (cfg *apiConfig) handlerBlocksCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeBlocksWrite)
+	if !ok {
+		return
+	}
+
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	if blockedID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't block yourself", nil)
+		return
+	}
+
+	if _, err := cfg.db.GetUser(r.Context(), blockedID); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Blocking twice is a no-op
+	err = qtx.CreateUserBlock(r.Context(), database.CreateUserBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't block user", err)
+		return
+	}
+	err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+		UserA: userID,
+		UserB: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't remove follows", err)
+		return
+	}
+	err = qtx.DeleteFollowRequestsBetween(r.Context(), database.DeleteFollowRequestsBetweenParams{
+		UserA: userID,
+		UserB: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't remove follow requests", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerBlocksDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeBlocksWrite)
+	if !ok {
+		return
+	}
+
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteUserBlock(r.Context(), database.DeleteUserBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unblock user", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Block not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerBlocksGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeBlocksRead)
+	if !ok {
+		return
+	}
+
+	blocks, err := cfg.db.ListBlockedUsers(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve blocked users", err)
+		return
+	}
+
+	results := []UserBlock{}
+	for _, block := range blocks {
+		results = append(results, UserBlock{
+			UserID:    block.BlockedID,
+			CreatedAt: block.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
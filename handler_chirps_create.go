@@ -7,75 +7,98 @@ import (
 	"strings"
 	"time"
 
-	"workspace/github.com/kozykoding/chirpy/internal/auth"
-	"workspace/github.com/kozykoding/chirpy/internal/database"
-
-	"github.com/google/uuid"
+	"workspace/github.com/kozykoding/chirpy/internal/chirptext"
+	"workspace/github.com/kozykoding/chirpy/internal/entitlements"
 )
 
-type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
-}
-
 func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		chirpAudience
+		Body string          `json:"body"`
+		Poll *pollParameters `json:"poll"`
 	}
 
-	// 1. Authenticate via Header
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+	// 1. Authenticate; tokens need chirps:write
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	user, err := cfg.db.GetUser(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+	if cfg.requireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Verify your email address before chirping", nil)
 		return
 	}
+	caps := cfg.entitlements.For(entitlements.PlanFor(user.IsChirpyRed))
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	if err := decoder.Decode(&params); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+	if !decodeTextBody(w, r, &params) {
 		return
 	}
 
-	// 2. Ported Validation Logic
-	cleaned, err := validateChirp(params.Body)
+	// 2. Ported Validation Logic, with the length limit from the user's plan
+	cleaned, err := validateChirp(params.Body, caps.MaxChirpLength)
 	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+	if err := params.chirpAudience.validate(userID); err != nil {
 		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
+	if params.Poll != nil {
+		if err := params.Poll.validate(time.Now()); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
 
-	// 3. Create Chirp using Authenticated UserID
-	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
-	})
+	// 3. Create Chirp (and its poll) using Authenticated UserID
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	chirp, err := cfg.createChirp(r.Context(), qtx, userID, cleaned, params.chirpAudience)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
 		return
 	}
+	if params.Poll != nil {
+		if err := createPoll(r.Context(), qtx, chirp.ID, *params.Poll); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create poll", err)
+			return
+		}
+	}
 
-	respondWithJSON(w, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), userID, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+	cfg.emitEvent(r.Context(), userID, eventChirpCreated, resp)
+
+	respondWithJSON(w, http.StatusCreated, resp)
 }
 
-func validateChirp(body string) (string, error) {
-	const maxChirpLength = 140
-	if len(body) > maxChirpLength {
-		return "", errors.New("Chirp is too long")
+// validateChirp normalizes the body and checks its length in characters, as
+// counted by chirptext, before cleaning it up
+func validateChirp(body string, maxChirpLength int) (string, error) {
+	body, err := chirptext.Validate(body, maxChirpLength)
+	if err != nil {
+		return "", err
 	}
 
 	badWords := map[string]struct{}{
@@ -87,6 +110,49 @@ func validateChirp(body string) (string, error) {
 	return cleaned, nil
 }
 
+// maxTextBodyBytes caps request bodies that carry a chirp, draft or message.
+// The longest text allowed is a few thousand characters, so this leaves room
+// for multi-byte characters and the rest of the JSON.
+const maxTextBodyBytes = 64 << 10
+
+// decodeTextBody decodes a JSON body of at most maxTextBodyBytes into params,
+// responding with an error and returning false if it can't
+func decodeTextBody(w http.ResponseWriter, r *http.Request, params any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxTextBodyBytes)
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(params); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Request body is too large", err)
+			return false
+		}
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return false
+	}
+	return true
+}
+
+// respondWithChirpError reports a chirp body that failed validation. Chirps
+// over the length limit say by how much, so clients can point at the overflow.
+func respondWithChirpError(w http.ResponseWriter, err error) {
+	var tooLong *chirptext.TooLongError
+	if !errors.As(err, &tooLong) {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	respondWithJSON(w, http.StatusBadRequest, struct {
+		Error  string `json:"error"`
+		Length int    `json:"length"`
+		Limit  int    `json:"limit"`
+		Over   int    `json:"over"`
+	}{
+		Error:  tooLong.Error(),
+		Length: tooLong.Length,
+		Limit:  tooLong.Limit,
+		Over:   tooLong.Over(),
+	})
+}
+
 func getCleanedBody(body string, badWords map[string]struct{}) string {
 	words := strings.Split(body, " ")
 	for i, word := range words {
@@ -4,23 +4,15 @@ import (
 	"database/sql"
 	"net/http"
 
-	"workspace/github.com/kozykoding/chirpy/internal/auth"
 	"workspace/github.com/kozykoding/chirpy/internal/database"
 
 	"github.com/google/uuid"
 )
 
 func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request) {
-	// 1. Authenticate via Access Token (JWT)
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+	// 1. Authenticate; deleting is a chirps:write action too
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
 		return
 	}
 
@@ -32,8 +24,12 @@ func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// 3. Fetch the chirp first to check ownership (and existence)
-	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	// 3. Fetch the chirp first to check ownership (and existence). Chirps
+	// the caller can't see are a 404, not a 403.
+	dbChirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			respondWithError(w, http.StatusNotFound, "Chirp not found", err)
@@ -49,6 +45,13 @@ func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Remote followers were sent the chirp if anyone could see it
+	federated, err := isPublicChirp(r.Context(), cfg.db, chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+
 	// 5. Delete the Chirp
 	err = cfg.db.DeleteChirp(r.Context(), database.DeleteChirpParams{
 		ID:     chirpID,
@@ -59,6 +62,17 @@ func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	cfg.emitEvent(r.Context(), userID, eventChirpDeleted, struct {
+		ID     uuid.UUID `json:"id"`
+		UserID uuid.UUID `json:"user_id"`
+	}{
+		ID:     chirpID,
+		UserID: userID,
+	})
+	if federated {
+		cfg.federateChirpDeleted(r.Context(), dbChirp)
+	}
+
 	// 6. Respond with 204 No Content
 	w.WriteHeader(http.StatusNoContent)
 }
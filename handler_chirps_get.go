@@ -1,9 +1,9 @@
 package main
 
 import (
+	"database/sql"
 	"net/http"
 	"sort"
-	"time"
 
 	"workspace/github.com/kozykoding/chirpy/internal/database"
 
@@ -16,6 +16,9 @@ func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 
 	var chirps []database.Chirp
 	var err error
+	pinnedID := uuid.Nil
+	// Chirps the viewer isn't allowed to see are left out entirely
+	viewerID := cfg.viewerID(r)
 
 	if authorID != "" {
 		uuidVal, err := uuid.Parse(authorID)
@@ -23,9 +26,26 @@ func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
 			return
 		}
-		chirps, err = cfg.db.GetChirpsByAuthor(r.Context(), uuidVal)
+		chirps, err = cfg.db.GetChirpsByAuthor(r.Context(), database.GetChirpsByAuthorParams{
+			UserID:   uuidVal,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+			return
+		}
+
+		// A profile feed shows the author's pinned chirp first
+		author, err := cfg.db.GetUser(r.Context(), uuidVal)
+		if err != nil && err != sql.ErrNoRows {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve author", err)
+			return
+		}
+		if author.PinnedChirpID.Valid {
+			pinnedID = author.PinnedChirpID.UUID
+		}
 	} else {
-		chirps, err = cfg.db.GetChirps(r.Context())
+		chirps, err = cfg.db.GetChirps(r.Context(), viewerID)
 	}
 
 	if err != nil {
@@ -33,23 +53,10 @@ func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type Chirp struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
-	}
-
-	results := []Chirp{}
-	for _, chirp := range chirps {
-		results = append(results, Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+	results, err := cfg.chirpResponses(r.Context(), viewerID, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
 	}
 
 	// Sort the slice in-memory
@@ -61,5 +68,13 @@ func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 		return results[i].CreatedAt.Before(results[j].CreatedAt)
 	})
 
+	for i, chirp := range results {
+		if chirp.ID == pinnedID {
+			chirp.Pinned = true
+			results = append([]Chirp{chirp}, append(results[:i:i], results[i+1:]...)...)
+			break
+		}
+	}
+
 	respondWithJSON(w, http.StatusOK, results)
 }
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"net/http"
 
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
 	"github.com/google/uuid"
 )
 
@@ -18,10 +20,16 @@ func (cfg *apiConfig) handlerChirpsGetSingle(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// 3. Retrieve the chirp from the database
-	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	// 3. Retrieve the chirp from the database, as long as the viewer may
+	// see it
+	viewerID := cfg.viewerID(r)
+	dbChirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
 	if err != nil {
-		// If SQLC returns no rows, send a 404
+		// If SQLC returns no rows, send a 404. Chirps the viewer can't see
+		// are indistinguishable from ones that don't exist.
 		if err == sql.ErrNoRows {
 			respondWithError(w, http.StatusNotFound, "Chirp not found", err)
 			return
@@ -31,11 +39,10 @@ func (cfg *apiConfig) handlerChirpsGetSingle(w http.ResponseWriter, r *http.Requ
 	}
 
 	// 4. Respond with 200 OK and the mapped Chirp
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	})
+	chirp, err := cfg.chirpResponse(r.Context(), viewerID, dbChirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirp)
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/entitlements"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerChirpsUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	// 1. Authenticate, then check the user's plan allows editing
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+	caps, ok := cfg.requireFeature(w, r, userID, entitlements.FeatureChirpEditing)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	params := parameters{}
+	if !decodeTextBody(w, r, &params) {
+		return
+	}
+
+	// 2. Fetch the chirp first to check ownership (and existence). Chirps
+	// the caller can't see are a 404, not a 403.
+	dbChirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+	if dbChirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not the author of this chirp", nil)
+		return
+	}
+
+	// 3. Same validation as a new chirp
+	cleaned, err := validateChirp(params.Body, caps.MaxChirpLength)
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+
+	// 4. Update the body and re-parse its links and hashtags together
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	chirp, err := qtx.UpdateChirp(r.Context(), database.UpdateChirpParams{
+		ID:     chirpID,
+		UserID: userID,
+		Body:   cleaned,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	if err := saveChirpLinks(r.Context(), qtx, chirp.ID, chirp.Body); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save chirp links", err)
+		return
+	}
+	if err := saveChirpHashtags(r.Context(), qtx, chirp.ID, chirp.Body); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save chirp hashtags", err)
+		return
+	}
+	if err := cfg.federateChirpUpdated(r.Context(), qtx, chirp); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't federate chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), userID, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// dataExportLinkTTL is how long a download link works. Links are handed out
+// after logging in, so they can be short-lived.
+const dataExportLinkTTL = 15 * time.Minute
+
+// DataExport is an archive of the caller's account. DownloadURL is set once
+// it's ready, and works without an access token until it expires.
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+func (cfg *apiConfig) dataExportResponse(export database.GetLatestDataExportRow) DataExport {
+	resp := DataExport{
+		ID:        export.ID,
+		CreatedAt: export.CreatedAt,
+		Status:    export.Status,
+	}
+	if export.SizeBytes.Valid {
+		resp.SizeBytes = &export.SizeBytes.Int64
+	}
+	if export.CompletedAt.Valid {
+		resp.CompletedAt = &export.CompletedAt.Time
+	}
+	if export.ExpiresAt.Valid {
+		resp.ExpiresAt = &export.ExpiresAt.Time
+	}
+	if export.Status == "ready" {
+		resp.DownloadURL = cfg.dataExportDownloadURL(export.ID, time.Now().Add(dataExportLinkTTL))
+	}
+	return resp
+}
+
+func (cfg *apiConfig) dataExportDownloadURL(exportID uuid.UUID, expires time.Time) string {
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires.Unix(), 10)},
+		"signature": {auth.SignLink(cfg.jwtSecret, "data-export", exportID.String(), expires)},
+	}
+	return cfg.baseURL + "/api/exports/" + exportID.String() + "/download?" + query.Encode()
+}
+
+// handlerDataExportCreate starts building an archive of the caller's
+// account. Asking again while one is being built returns that one.
+func (cfg *apiConfig) handlerDataExportCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeSession)
+	if !ok {
+		return
+	}
+
+	latest, err := cfg.db.GetLatestDataExport(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve data export", err)
+		return
+	}
+	if err == nil && latest.Status == "pending" {
+		respondWithJSON(w, http.StatusAccepted, cfg.dataExportResponse(latest))
+		return
+	}
+
+	export, err := cfg.db.CreateDataExport(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create data export", err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, cfg.dataExportResponse(database.GetLatestDataExportRow(export)))
+}
+
+// handlerDataExportGet reports on the caller's latest archive, with a fresh
+// download link once it's ready
+func (cfg *apiConfig) handlerDataExportGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeSession)
+	if !ok {
+		return
+	}
+
+	export, err := cfg.db.GetLatestDataExport(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "No data export requested", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve data export", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, cfg.dataExportResponse(export))
+}
+
+// handlerDataExportDownload serves an archive to anyone holding a valid
+// download link. The signed link stands in for the access token so it can
+// be opened in a browser.
+func (cfg *apiConfig) handlerDataExportDownload(w http.ResponseWriter, r *http.Request) {
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID", err)
+		return
+	}
+
+	// 1. Check the link
+	expiresUnix, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Invalid or expired download link", err)
+		return
+	}
+	err = auth.VerifyLink(r.URL.Query().Get("signature"), cfg.jwtSecret, "data-export", exportID.String(), time.Unix(expiresUnix, 0), time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Invalid or expired download link", err)
+		return
+	}
+
+	// 2. Load the archive, which is gone once the export expires
+	archive, err := cfg.db.GetDataExportArchive(r.Context(), exportID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Data export not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve data export", err)
+		return
+	}
+
+	// 3. Serve it as a download
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export-`+exportID.String()+`.zip"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
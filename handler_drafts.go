@@ -0,0 +1,313 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/entitlements"
+
+	"github.com/google/uuid"
+)
+
+// Draft is an unpublished chirp. Drafts with a publish_at are scheduled and
+// get published by the scheduler job.
+type Draft struct {
+	ID         uuid.UUID   `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Body       string      `json:"body"`
+	Visibility string      `json:"visibility"`
+	Mentions   []uuid.UUID `json:"mentions"`
+	PublishAt  *time.Time  `json:"publish_at"`
+	Scheduled  bool        `json:"scheduled"`
+}
+
+func draftResponse(draft database.Draft) Draft {
+	resp := Draft{
+		ID:         draft.ID,
+		CreatedAt:  draft.CreatedAt,
+		UpdatedAt:  draft.UpdatedAt,
+		Body:       draft.Body,
+		Visibility: draft.Visibility,
+		Mentions:   draft.Mentions,
+		Scheduled:  draft.PublishAt.Valid,
+	}
+	if draft.PublishAt.Valid {
+		resp.PublishAt = &draft.PublishAt.Time
+	}
+	return resp
+}
+
+// draftParameters is the body of both create and update
+type draftParameters struct {
+	chirpAudience
+	Body      string     `json:"body"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// validateDraft checks the body against the user's plan and, for scheduled
+// drafts, that the plan allows scheduling and the time is in the future
+func (cfg *apiConfig) validateDraft(w http.ResponseWriter, r *http.Request, userID uuid.UUID, params *draftParameters) (sql.NullTime, bool) {
+	caps, err := cfg.capabilitiesFor(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check your plan", err)
+		return sql.NullTime{}, false
+	}
+	if _, err := validateChirp(params.Body, caps.MaxChirpLength); err != nil {
+		respondWithChirpError(w, err)
+		return sql.NullTime{}, false
+	}
+	if err := params.chirpAudience.validate(userID); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return sql.NullTime{}, false
+	}
+
+	if params.PublishAt == nil {
+		return sql.NullTime{}, true
+	}
+	if _, ok := cfg.requireFeature(w, r, userID, entitlements.FeatureScheduledChirps); !ok {
+		return sql.NullTime{}, false
+	}
+	if !params.PublishAt.After(time.Now()) {
+		respondWithError(w, http.StatusBadRequest, "publish_at must be in the future", nil)
+		return sql.NullTime{}, false
+	}
+	return sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}, true
+}
+
+func (cfg *apiConfig) handlerDraftsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	params := draftParameters{}
+	if !decodeTextBody(w, r, &params) {
+		return
+	}
+
+	publishAt, ok := cfg.validateDraft(w, r, userID, &params)
+	if !ok {
+		return
+	}
+
+	draft, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID:     userID,
+		Body:       params.Body,
+		PublishAt:  publishAt,
+		Visibility: params.Visibility,
+		Mentions:   params.Mentions,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, draftResponse(draft))
+}
+
+// handlerDraftsGet lists drafts, scheduled ones first in publishing order.
+// ?scheduled=true or false narrows the list down.
+func (cfg *apiConfig) handlerDraftsGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	drafts, err := cfg.db.ListDrafts(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve drafts", err)
+		return
+	}
+
+	scheduled := r.URL.Query().Get("scheduled")
+	results := []Draft{}
+	for _, draft := range drafts {
+		if (scheduled == "true" && !draft.PublishAt.Valid) || (scheduled == "false" && draft.PublishAt.Valid) {
+			continue
+		}
+		results = append(results, draftResponse(draft))
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func (cfg *apiConfig) handlerDraftsGetSingle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID", err)
+		return
+	}
+
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Draft not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, draftResponse(draft))
+}
+
+// handlerDraftsUpdate replaces the body and schedule. Sending no publish_at
+// unschedules the draft.
+func (cfg *apiConfig) handlerDraftsUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID", err)
+		return
+	}
+
+	params := draftParameters{}
+	if !decodeTextBody(w, r, &params) {
+		return
+	}
+
+	publishAt, ok := cfg.validateDraft(w, r, userID, &params)
+	if !ok {
+		return
+	}
+
+	// A draft the scheduler already published is gone, so this is a 404
+	draft, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:         draftID,
+		UserID:     userID,
+		Body:       params.Body,
+		PublishAt:  publishAt,
+		Visibility: params.Visibility,
+		Mentions:   params.Mentions,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Draft not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, draftResponse(draft))
+}
+
+// handlerDraftsDelete discards a draft, cancelling it if it was scheduled
+func (cfg *apiConfig) handlerDraftsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteDraft(r.Context(), database.DeleteDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete draft", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Draft not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerDraftsPublish publishes a draft right away
+func (cfg *apiConfig) handlerDraftsPublish(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID", err)
+		return
+	}
+
+	// Publishing is chirping, so it needs the same verified email
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+	if cfg.requireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Verify your email address before chirping", nil)
+		return
+	}
+	caps := cfg.entitlements.For(entitlements.PlanFor(user.IsChirpyRed))
+
+	// Taking the draft (DELETE ... RETURNING) and creating the chirp in one
+	// transaction means it can't also be published by the scheduler
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	draft, err := qtx.TakeDraft(r.Context(), database.TakeDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Draft not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve draft", err)
+		return
+	}
+
+	cleaned, err := validateChirp(draft.Body, caps.MaxChirpLength)
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+	chirp, err := cfg.createChirp(r.Context(), qtx, userID, cleaned, chirpAudience{
+		Visibility: draft.Visibility,
+		Mentions:   draft.Mentions,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), userID, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+	cfg.emitEvent(r.Context(), userID, eventChirpCreated, resp)
+
+	respondWithJSON(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"workspace/github.com/kozykoding/chirpy/internal/chirptext"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/feed"
+
+	"github.com/google/uuid"
+)
+
+const (
+	feedEntryLimit          = 20
+	feedEntryTitleMaxLength = 80
+)
+
+const (
+	feedFormatAtom = "atom"
+	feedFormatRSS  = "rss"
+)
+
+func (cfg *apiConfig) handlerUserFeedAtom(w http.ResponseWriter, r *http.Request) {
+	cfg.serveUserFeed(w, r, feedFormatAtom)
+}
+
+func (cfg *apiConfig) handlerUserFeedRSS(w http.ResponseWriter, r *http.Request) {
+	cfg.serveUserFeed(w, r, feedFormatRSS)
+}
+
+func (cfg *apiConfig) handlerHashtagFeedAtom(w http.ResponseWriter, r *http.Request) {
+	cfg.serveHashtagFeed(w, r, feedFormatAtom)
+}
+
+func (cfg *apiConfig) handlerHashtagFeedRSS(w http.ResponseWriter, r *http.Request) {
+	cfg.serveHashtagFeed(w, r, feedFormatRSS)
+}
+
+func (cfg *apiConfig) serveUserFeed(w http.ResponseWriter, r *http.Request, format string) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+	// An account pending deletion is gone as far as the public can tell
+	if user.DeletionScheduledAt.Valid {
+		respondWithError(w, http.StatusNotFound, "User not found", nil)
+		return
+	}
+
+	chirps, err := cfg.db.GetLatestChirpsByAuthor(r.Context(), database.GetLatestChirpsByAuthorParams{
+		UserID:     userID,
+		ViewerID:   uuid.Nil,
+		MaxResults: feedEntryLimit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	cfg.serveFeed(w, r, format, feed.Feed{
+		Title:   "Chirps by " + user.ID.String(),
+		Link:    cfg.baseURL + "/api/chirps?author_id=" + user.ID.String() + "&sort=desc",
+		Updated: user.CreatedAt,
+		Entries: cfg.feedEntries(chirps),
+	})
+}
+
+func (cfg *apiConfig) serveHashtagFeed(w http.ResponseWriter, r *http.Request, format string) {
+	// The tag is matched the way it's extracted from chirps
+	tag := strings.TrimPrefix(chirptext.Normalize(r.PathValue("tag")), "#")
+	tags := chirptext.Hashtags("#" + tag)
+	if len(tags) != 1 || tags[0] != strings.ToLower(tag) {
+		respondWithError(w, http.StatusBadRequest, "Invalid hashtag", nil)
+		return
+	}
+
+	chirps, err := cfg.db.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+		Tag:        tags[0],
+		ViewerID:   uuid.Nil,
+		MaxResults: feedEntryLimit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	cfg.serveFeed(w, r, format, feed.Feed{
+		Title:   "Chirps tagged #" + tags[0],
+		Link:    cfg.baseURL + "/app/",
+		Entries: cfg.feedEntries(chirps),
+	})
+}
+
+// serveFeed fills in the feed's own URLs and renders it in the requested
+// format. Feed readers fetch feeds without credentials, so feeds are only
+// ever built from chirps anyone can see.
+func (cfg *apiConfig) serveFeed(w http.ResponseWriter, r *http.Request, format string, f feed.Feed) {
+	f.SelfURL = cfg.baseURL + r.URL.EscapedPath()
+	f.ID = f.SelfURL
+
+	render, contentType := f.Atom, feed.AtomContentType
+	if format == feedFormatRSS {
+		render, contentType = f.RSS, feed.RSSContentType
+	}
+	body, err := render()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't render feed", err)
+		return
+	}
+	feed.Serve(w, r, f, contentType, body)
+}
+
+// feedEntries converts chirps, newest first. Chirp IDs never change, so they
+// make permanent entry IDs.
+func (cfg *apiConfig) feedEntries(chirps []database.Chirp) []feed.Entry {
+	entries := make([]feed.Entry, 0, len(chirps))
+	for _, chirp := range chirps {
+		entries = append(entries, feed.Entry{
+			ID:        "urn:uuid:" + chirp.ID.String(),
+			Title:     feedEntryTitle(chirp.Body),
+			Link:      cfg.baseURL + "/api/chirps/" + chirp.ID.String(),
+			Author:    chirp.UserID.String(),
+			Content:   chirp.Body,
+			Published: chirp.CreatedAt,
+			Updated:   chirp.UpdatedAt,
+		})
+	}
+	return entries
+}
+
+// feedEntryTitle is the chirp's first line, shortened if long
+func feedEntryTitle(body string) string {
+	title, _, _ := strings.Cut(body, "\n")
+	if utf8.RuneCountInString(title) > feedEntryTitleMaxLength {
+		title = string([]rune(title)[:feedEntryTitleMaxLength-1]) + "…"
+	}
+	return title
+}
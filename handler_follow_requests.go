@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type FollowRequest struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handlerPrivacyUpdate makes the caller's account private or public. Going
+// public approves every pending follow request, since anyone could now
+// follow without asking.
+func (cfg *apiConfig) handlerPrivacyUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IsPrivate bool `json:"is_private"`
+	}
+
+	userID, ok := cfg.authenticate(w, r, scopeProfileWrite)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.SetUserPrivate(r.Context(), database.SetUserPrivateParams{
+		ID:        userID,
+		IsPrivate: params.IsPrivate,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+		return
+	}
+	approved := []uuid.UUID{}
+	if !user.IsPrivate {
+		approved, err = qtx.ApproveAllFollowRequests(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't approve follow requests", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	for _, followerID := range approved {
+		cfg.emitFollowed(r.Context(), followerID, userID)
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		IsChirpyRed:   user.IsChirpyRed,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		IsPrivate:     user.IsPrivate,
+	})
+}
+
+// handlerFollowRequestsGet lists pending requests to follow the caller,
+// oldest first
+func (cfg *apiConfig) handlerFollowRequestsGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	requests, err := cfg.db.ListFollowRequests(r.Context(), database.ListFollowRequestsParams{
+		TargetID: userID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve follow requests", err)
+		return
+	}
+
+	results := []FollowRequest{}
+	for _, request := range requests {
+		results = append(results, FollowRequest{
+			UserID:    request.RequesterID,
+			CreatedAt: request.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// handlerFollowRequestsApprove turns a pending request into a follow
+func (cfg *apiConfig) handlerFollowRequestsApprove(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	requesterID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	_, err = qtx.TakeFollowRequest(r.Context(), database.TakeFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Follow request not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve follow request", err)
+		return
+	}
+	created, err := qtx.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: requesterID,
+		FolloweeID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't approve follow request", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	if created > 0 {
+		cfg.emitFollowed(r.Context(), requesterID, userID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerFollowRequestsDeny(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	requesterID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't deny follow request", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Follow request not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Follow is one side of a follow relationship: the follower in a followers
+// list, the followee in a following list
+type Follow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handlerFollowsCreate follows a user, which lets the caller read their
+// followers-only chirps. Following a private account only sends a follow
+// request, answered with 202 until the owner approves it.
+func (cfg *apiConfig) handlerFollowsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	followee, err := cfg.db.GetUser(r.Context(), followeeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	blocked, err := cfg.db.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+		UserA: userID,
+		UserB: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "You can't follow this user", nil)
+		return
+	}
+
+	if followee.IsPrivate {
+		// Requesting twice is a no-op too
+		_, err := cfg.db.CreateFollowRequest(r.Context(), database.CreateFollowRequestParams{
+			RequesterID: userID,
+			TargetID:    followeeID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't request to follow user", err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Following twice is a no-op, and only a new follow is announced
+	created, err := cfg.db.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+	if created > 0 {
+		cfg.emitFollowed(r.Context(), userID, followeeID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// emitFollowed tells the followee's webhook endpoints about a new follower
+func (cfg *apiConfig) emitFollowed(ctx context.Context, followerID, followeeID uuid.UUID) {
+	cfg.emitEvent(ctx, followeeID, eventUserFollowed, struct {
+		FollowerID uuid.UUID `json:"follower_id"`
+		FolloweeID uuid.UUID `json:"followee_id"`
+	}{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+}
+
+// handlerFollowsDelete unfollows a user, or withdraws a pending follow
+// request
+func (cfg *apiConfig) handlerFollowsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteFollow(r.Context(), database.DeleteFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+	if deleted == 0 {
+		deleted, err = cfg.db.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+			RequesterID: userID,
+			TargetID:    followeeID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't withdraw follow request", err)
+			return
+		}
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "You don't follow this user", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canSeeFollows checks that the caller may list who the user follows and is
+// followed by. A private account's lists are only shown to the account itself
+// and its approved followers, like its chirps.
+func (cfg *apiConfig) canSeeFollows(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return false
+	}
+	viewerID := cfg.viewerID(r)
+	if !user.IsPrivate || viewerID == userID {
+		return true
+	}
+
+	following, err := cfg.db.IsFollowing(r.Context(), database.IsFollowingParams{
+		FollowerID: viewerID,
+		FolloweeID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check follows", err)
+		return false
+	}
+	if !following {
+		respondWithError(w, http.StatusForbidden, "This account is private", nil)
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) handlerFollowersGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	if !cfg.canSeeFollows(w, r, userID) {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	follows, err := cfg.db.ListFollowers(r.Context(), database.ListFollowersParams{
+		FolloweeID: userID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve followers", err)
+		return
+	}
+
+	results := []Follow{}
+	for _, follow := range follows {
+		results = append(results, Follow{
+			UserID:    follow.FollowerID,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func (cfg *apiConfig) handlerFollowingGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	if !cfg.canSeeFollows(w, r, userID) {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	follows, err := cfg.db.ListFollowing(r.Context(), database.ListFollowingParams{
+		FollowerID: userID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve followed users", err)
+		return
+	}
+
+	results := []Follow{}
+	for _, follow := range follows {
+		results = append(results, Follow{
+			UserID:    follow.FolloweeID,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxListsPerUser    = 100
+	maxListMembers     = 500
+	maxListNameLength  = 50
+	maxListDescription = 280
+)
+
+// List is a user-curated group of accounts. Private lists are only visible
+// to their owner, and stop showing up for anyone who followed them while
+// they were public.
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+}
+
+func listResponse(list database.List) List {
+	return List{
+		ID:          list.ID,
+		CreatedAt:   list.CreatedAt,
+		UpdatedAt:   list.UpdatedAt,
+		UserID:      list.UserID,
+		Name:        list.Name,
+		Description: list.Description,
+		Private:     list.IsPrivate,
+	}
+}
+
+func listResponses(lists []database.List) []List {
+	results := make([]List, 0, len(lists))
+	for _, list := range lists {
+		results = append(results, listResponse(list))
+	}
+	return results
+}
+
+type ListMember struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// listParameters is the body of both create and update
+type listParameters struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+func (p *listParameters) validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("List name is required")
+	}
+	if len(p.Name) > maxListNameLength {
+		return fmt.Errorf("List name can be at most %d characters", maxListNameLength)
+	}
+	if len(p.Description) > maxListDescription {
+		return fmt.Errorf("List description can be at most %d characters", maxListDescription)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerListsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := listParameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if err := params.validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	count, err := cfg.db.CountListsByOwner(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count lists", err)
+		return
+	}
+	if count >= maxListsPerUser {
+		msg := fmt.Sprintf("You can have at most %d lists", maxListsPerUser)
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+
+	list, err := cfg.db.CreateList(r.Context(), database.CreateListParams{
+		UserID:      userID,
+		Name:        params.Name,
+		Description: params.Description,
+		IsPrivate:   params.Private,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create list", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, listResponse(list))
+}
+
+// handlerListsGet lists the caller's own lists, private ones included
+func (cfg *apiConfig) handlerListsGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	lists, err := cfg.db.ListListsByOwner(r.Context(), database.ListListsByOwnerParams{
+		UserID:         userID,
+		IncludePrivate: true,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve lists", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, listResponses(lists))
+}
+
+// handlerListsGetFollowed lists the public lists the caller follows
+func (cfg *apiConfig) handlerListsGetFollowed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	lists, err := cfg.db.ListFollowedLists(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve lists", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, listResponses(lists))
+}
+
+// handlerUserListsGet lists a user's public lists, and their private ones
+// too when they are the viewer
+func (cfg *apiConfig) handlerUserListsGet(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	lists, err := cfg.db.ListListsByOwner(r.Context(), database.ListListsByOwnerParams{
+		UserID:         ownerID,
+		IncludePrivate: cfg.viewerID(r) == ownerID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve lists", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, listResponses(lists))
+}
+
+func (cfg *apiConfig) handlerListsGetSingle(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.getVisibleList(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, listResponse(list))
+}
+
+func (cfg *apiConfig) handlerListsUpdate(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.getOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := listParameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if err := params.validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	updated, err := cfg.db.UpdateList(r.Context(), database.UpdateListParams{
+		ID:          list.ID,
+		UserID:      list.UserID,
+		Name:        params.Name,
+		Description: params.Description,
+		IsPrivate:   params.Private,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "List not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update list", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, listResponse(updated))
+}
+
+func (cfg *apiConfig) handlerListsDelete(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.getOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := cfg.db.DeleteList(r.Context(), database.DeleteListParams{
+		ID:     list.ID,
+		UserID: list.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete list", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "List not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListMembersCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+
+	list, ok := cfg.getOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	if _, err := cfg.db.GetUser(r.Context(), params.UserID); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	count, err := cfg.db.CountListMembers(r.Context(), list.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count list members", err)
+		return
+	}
+	if count >= maxListMembers {
+		msg := fmt.Sprintf("Lists can have at most %d members", maxListMembers)
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+
+	// Adding someone twice is a no-op
+	_, err = cfg.db.AddListMember(r.Context(), database.AddListMemberParams{
+		ListID: list.ID,
+		UserID: params.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't add list member", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListMembersDelete(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.getOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	removed, err := cfg.db.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't remove list member", err)
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, "List member not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListMembersGet(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.getVisibleList(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := cfg.db.ListListMembers(r.Context(), list.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve list members", err)
+		return
+	}
+
+	results := []ListMember{}
+	for _, member := range members {
+		results = append(results, ListMember{
+			UserID:    member.UserID,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// handlerListChirpsGet is the list timeline: chirps by its members, newest
+// first
+func (cfg *apiConfig) handlerListChirpsGet(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.getVisibleList(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+	chirps, err := cfg.db.GetListChirps(r.Context(), database.GetListChirpsParams{
+		ListID:   list.ID,
+		ViewerID: viewerID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	results, err := cfg.chirpResponses(r.Context(), viewerID, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func (cfg *apiConfig) handlerListsFollow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID", err)
+		return
+	}
+
+	list, err := cfg.db.GetList(r.Context(), listID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve list", err)
+		return
+	}
+	if err == sql.ErrNoRows || (list.IsPrivate && list.UserID != userID) {
+		respondWithError(w, http.StatusNotFound, "List not found", err)
+		return
+	}
+	if list.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow your own list", nil)
+		return
+	}
+	if list.IsPrivate {
+		respondWithError(w, http.StatusBadRequest, "Private lists can't be followed", nil)
+		return
+	}
+
+	// Following twice is a no-op
+	_, err = cfg.db.FollowList(r.Context(), database.FollowListParams{
+		ListID: list.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow list", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListsUnfollow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID", err)
+		return
+	}
+
+	deleted, err := cfg.db.UnfollowList(r.Context(), database.UnfollowListParams{
+		ListID: listID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow list", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "You don't follow this list", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getVisibleList loads the list in the path, responding with 404 if it is
+// private and the viewer isn't its owner
+func (cfg *apiConfig) getVisibleList(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID", err)
+		return database.List{}, false
+	}
+
+	list, err := cfg.db.GetList(r.Context(), listID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve list", err)
+		return database.List{}, false
+	}
+	if err == sql.ErrNoRows || (list.IsPrivate && list.UserID != cfg.viewerID(r)) {
+		respondWithError(w, http.StatusNotFound, "List not found", err)
+		return database.List{}, false
+	}
+	return list, true
+}
+
+// getOwnList authenticates the caller and loads the list in the path,
+// responding with 404 if it belongs to someone else
+func (cfg *apiConfig) getOwnList(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return database.List{}, false
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID", err)
+		return database.List{}, false
+	}
+
+	list, err := cfg.db.GetList(r.Context(), listID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve list", err)
+		return database.List{}, false
+	}
+	if err == sql.ErrNoRows || list.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "List not found", err)
+		return database.List{}, false
+	}
+	return list, true
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"workspace/github.com/kozykoding/chirpy/internal/auth"
@@ -11,6 +18,23 @@ import (
 	"github.com/google/uuid"
 )
 
+const mfaChallengeTTL = 5 * time.Minute
+
+var (
+	accountLockoutPolicy = auth.LockoutPolicy{
+		BackoffAfter: 3,
+		LockoutAfter: 10,
+		BaseDelay:    time.Second,
+		Lockout:      15 * time.Minute,
+	}
+	ipLockoutPolicy = auth.LockoutPolicy{
+		BackoffAfter: 10,
+		LockoutAfter: 50,
+		BaseDelay:    time.Second,
+		Lockout:      15 * time.Minute,
+	}
+)
+
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
@@ -24,18 +48,72 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Failures are tracked by email whether or not the account exists, so a
+	// lockout doesn't reveal which emails are registered
+	accountKey := "email:" + strings.ToLower(params.Email)
+	ipKey := "ip:" + clientIP(r)
+	if !cfg.checkLoginLockout(w, r, accountKey, ipKey) {
+		return
+	}
+
 	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
+		// Burn the same time as a real hash check
+		auth.CheckDummyPasswordHash(params.Password)
+		cfg.recordLoginFailure(r, accountKey, ipKey, nil)
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
 	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
 	if err != nil || !match {
+		cfg.recordLoginFailure(r, accountKey, ipKey, &user)
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
+	// Accounts with 2FA get a short-lived challenge instead of a session. Failures
+	// are only cleared once the second step succeeds too.
+	totp, err := cfg.db.GetUserTOTP(r.Context(), user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor authentication", err)
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		mfaToken, err := auth.MakeMFAToken(user.ID, cfg.jwtSecret, mfaChallengeTTL)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create MFA token", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, struct {
+			MFARequired bool   `json:"mfa_required"`
+			MFAToken    string `json:"mfa_token"`
+		}{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	if err := cfg.db.ClearLoginFailures(r.Context(), accountKey); err != nil {
+		log.Printf("Couldn't clear login failures: %s", err)
+	}
+
+	// Logging in keeps an account that was scheduled for deletion
+	if err := cfg.cancelAccountDeletion(r.Context(), user); err != nil {
+		if errors.Is(err, errAccountDeleted) {
+			respondWithError(w, http.StatusGone, "This account has been deleted", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't cancel account deletion", err)
+		return
+	}
+
+	cfg.respondWithSession(w, r, user)
+}
+
+// respondWithSession issues an access token and a refresh token for the user
+func (cfg *apiConfig) respondWithSession(w http.ResponseWriter, r *http.Request, user database.User) {
 	// 1. Create 1-hour Access Token (JWT)
 	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
 	if err != nil {
@@ -62,20 +140,100 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondWithJSON(w, http.StatusOK, struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"` // <--- ADD THIS FIELD
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"` // <--- ADD THIS FIELD
+		EmailVerified bool      `json:"email_verified"`
+		IsPrivate     bool      `json:"is_private"`
+		Token         string    `json:"token"`
+		RefreshToken  string    `json:"refresh_token"`
 	}{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		IsChirpyRed:  user.IsChirpyRed, // <--- MAP THE VALUE
-		Token:        accessToken,
-		RefreshToken: refreshTokenStr,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		IsChirpyRed:   user.IsChirpyRed, // <--- MAP THE VALUE
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		IsPrivate:     user.IsPrivate,
+		Token:         accessToken,
+		RefreshToken:  refreshTokenStr,
 	})
 }
+
+// checkLoginLockout responds with 429 and returns false if any of the keys
+// is locked out. Anything that checks a password or a second factor goes
+// through it, so guesses can't be spread across endpoints.
+func (cfg *apiConfig) checkLoginLockout(w http.ResponseWriter, r *http.Request, keys ...string) bool {
+	for _, key := range keys {
+		retryAfter, err := cfg.loginRetryAfter(r.Context(), key)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+			return false
+		}
+		if retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			respondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+			return false
+		}
+	}
+	return true
+}
+
+// loginRetryAfter returns how long the key is still locked out for
+func (cfg *apiConfig) loginRetryAfter(ctx context.Context, key string) (time.Duration, error) {
+	failure, err := cfg.db.GetLoginFailure(ctx, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !failure.LockedUntil.Valid {
+		return 0, nil
+	}
+	return time.Until(failure.LockedUntil.Time), nil
+}
+
+// recordLoginFailure bumps the failure counters for the account and IP, and
+// emails the account owner when the account gets locked out
+func (cfg *apiConfig) recordLoginFailure(r *http.Request, accountKey, ipKey string, user *database.User) {
+	policies := map[string]auth.LockoutPolicy{
+		accountKey: accountLockoutPolicy,
+		ipKey:      ipLockoutPolicy,
+	}
+	for key, policy := range policies {
+		failure, err := cfg.db.RecordLoginFailure(r.Context(), key)
+		if err != nil {
+			log.Printf("Couldn't record login failure: %s", err)
+			continue
+		}
+
+		delay := policy.Delay(int(failure.Failures))
+		if delay == 0 {
+			continue
+		}
+		err = cfg.db.SetLoginLockout(r.Context(), database.SetLoginLockoutParams{
+			Key:         key,
+			LockedUntil: sql.NullTime{Time: time.Now().UTC().Add(delay), Valid: true},
+		})
+		if err != nil {
+			log.Printf("Couldn't set login lockout: %s", err)
+			continue
+		}
+
+		if key == accountKey && user != nil && policy.IsLockout(int(failure.Failures)) {
+			body := fmt.Sprintf(
+				"We noticed %d failed attempts to log in to your Chirpy account from %s.\n\n"+
+					"To protect you, logins are blocked for the next %s. "+
+					"If this wasn't you, consider changing your password once the lockout ends.\n",
+				failure.Failures, clientIP(r), policy.Lockout,
+			)
+			err := cfg.enqueueEmail(r.Context(), user.Email, "Your Chirpy account has been temporarily locked", body)
+			if err != nil {
+				log.Printf("Couldn't queue lockout email: %s", err)
+			}
+		}
+	}
+}
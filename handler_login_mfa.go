@@ -0,0 +1,118 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	// 1. The challenge token proves the password step succeeded
+	userID, err := auth.ValidateMFAToken(params.MFAToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+
+	// 2. Wrong codes count towards the same lockout as wrong passwords
+	accountKey := "email:" + strings.ToLower(user.Email)
+	ipKey := "ip:" + clientIP(r)
+	if !cfg.checkLoginLockout(w, r, accountKey, ipKey) {
+		return
+	}
+
+	// 3. Check the TOTP code or burn a recovery code
+	ok, err := cfg.checkSecondFactor(r, user.ID, params.Code, params.RecoveryCode)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor code", err)
+		return
+	}
+	if !ok {
+		cfg.recordLoginFailure(r, accountKey, ipKey, &user)
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code", nil)
+		return
+	}
+
+	if err := cfg.db.ClearLoginFailures(r.Context(), accountKey); err != nil {
+		log.Printf("Couldn't clear login failures: %s", err)
+	}
+
+	// Logging in keeps an account that was scheduled for deletion
+	if err := cfg.cancelAccountDeletion(r.Context(), user); err != nil {
+		if errors.Is(err, errAccountDeleted) {
+			respondWithError(w, http.StatusGone, "This account has been deleted", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't cancel account deletion", err)
+		return
+	}
+
+	cfg.respondWithSession(w, r, user)
+}
+
+// checkSecondFactor accepts either a current TOTP code, which can't be reused,
+// or an unused recovery code
+func (cfg *apiConfig) checkSecondFactor(r *http.Request, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if !totp.ConfirmedAt.Valid {
+		return false, nil
+	}
+
+	if recoveryCode != "" {
+		used, err := cfg.db.UseRecoveryCode(r.Context(), database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)),
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	step, ok := auth.ValidateTOTP(code, totp.Secret, time.Now())
+	if !ok {
+		return false, nil
+	}
+	updated, err := cfg.db.SetTOTPLastUsedStep(r.Context(), database.SetTOTPLastUsedStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated == 1, nil
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/chirptext"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Including the user who starts the conversation
+	maxConversationParticipants = 8
+	maxMessageLength            = 2000
+	defaultMessagePageSize      = 50
+	maxMessagePageSize          = 200
+)
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+func messageResponse(message database.Message) Message {
+	return Message{
+		ID:             message.ID,
+		CreatedAt:      message.CreatedAt,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+	}
+}
+
+// ConversationParticipant carries the participant's read receipt: the last
+// message they've read and when
+type ConversationParticipant struct {
+	UserID            uuid.UUID  `json:"user_id"`
+	JoinedAt          time.Time  `json:"joined_at"`
+	LastReadMessageID *uuid.UUID `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+}
+
+type Conversation struct {
+	ID           uuid.UUID                 `json:"id"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+	IsGroup      bool                      `json:"is_group"`
+	Participants []ConversationParticipant `json:"participants"`
+	LastMessage  *Message                  `json:"last_message"`
+	UnreadCount  int64                     `json:"unread_count"`
+}
+
+// conversationResponses converts conversations as seen by the viewer,
+// loading participants, last messages and unread counts in batches
+func (cfg *apiConfig) conversationResponses(ctx context.Context, viewerID uuid.UUID, conversations []database.Conversation) ([]Conversation, error) {
+	results := make([]Conversation, 0, len(conversations))
+	if len(conversations) == 0 {
+		return results, nil
+	}
+	conversationIDs := make([]uuid.UUID, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ID)
+	}
+
+	participants, err := cfg.db.ListConversationParticipants(ctx, conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+	participantsByConversation := map[uuid.UUID][]ConversationParticipant{}
+	for _, p := range participants {
+		resp := ConversationParticipant{
+			UserID:   p.UserID,
+			JoinedAt: p.JoinedAt,
+		}
+		if p.LastReadMessageID.Valid {
+			resp.LastReadMessageID = &p.LastReadMessageID.UUID
+		}
+		if p.LastReadAt.Valid {
+			resp.LastReadAt = &p.LastReadAt.Time
+		}
+		participantsByConversation[p.ConversationID] = append(participantsByConversation[p.ConversationID], resp)
+	}
+
+	lastMessages, err := cfg.db.GetLastMessages(ctx, database.GetLastMessagesParams{
+		ConversationIds: conversationIDs,
+		ViewerID:        viewerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lastMessageByConversation := map[uuid.UUID]Message{}
+	for _, message := range lastMessages {
+		lastMessageByConversation[message.ConversationID] = messageResponse(message)
+	}
+
+	unread, err := cfg.db.CountUnreadMessages(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	unreadByConversation := map[uuid.UUID]int64{}
+	for _, row := range unread {
+		unreadByConversation[row.ConversationID] = row.Unread
+	}
+
+	for _, conversation := range conversations {
+		resp := Conversation{
+			ID:           conversation.ID,
+			CreatedAt:    conversation.CreatedAt,
+			UpdatedAt:    conversation.UpdatedAt,
+			IsGroup:      conversation.IsGroup,
+			Participants: participantsByConversation[conversation.ID],
+			UnreadCount:  unreadByConversation[conversation.ID],
+		}
+		if message, ok := lastMessageByConversation[conversation.ID]; ok {
+			resp.LastMessage = &message
+		}
+		results = append(results, resp)
+	}
+	return results, nil
+}
+
+func validateMessage(body string) error {
+	if body == "" {
+		return fmt.Errorf("Message body is required")
+	}
+	// Counted like chirps, in characters rather than bytes
+	if chirptext.Length(chirptext.Normalize(body)) > maxMessageLength {
+		return fmt.Errorf("Message is too long")
+	}
+	return nil
+}
+
+// directConversationKey names the 1:1 conversation between two users, the
+// same whichever of them starts it
+func directConversationKey(a, b uuid.UUID) string {
+	if a.String() > b.String() {
+		a, b = b, a
+	}
+	return "conversation:" + a.String() + ":" + b.String()
+}
+
+// handlerConversationsCreate starts a conversation with the given users.
+// Starting a 1:1 conversation that already exists returns the existing one,
+// so clients can always "message this user" through this endpoint.
+func (cfg *apiConfig) handlerConversationsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+		Body           string      `json:"body"`
+	}
+
+	userID, ok := cfg.authenticate(w, r, scopeMessagesWrite)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeTextBody(w, r, &params) {
+		return
+	}
+
+	// 1. Validate the participants, leaving out the caller and duplicates
+	others := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, id := range params.ParticipantIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		others = append(others, id)
+	}
+	if len(others) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one other participant is required", nil)
+		return
+	}
+	if len(others)+1 > maxConversationParticipants {
+		msg := fmt.Sprintf("Conversations can have at most %d participants", maxConversationParticipants)
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+	if params.Body != "" {
+		if err := validateMessage(params.Body); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	// 2. Everyone must exist, and nobody in the conversation may have
+	// blocked (or been blocked by) anyone else in it
+	for _, id := range others {
+		if _, err := cfg.db.GetUser(r.Context(), id); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusBadRequest, "Unknown participant: "+id.String(), err)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+			return
+		}
+		blocked, err := cfg.db.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+			UserA: userID,
+			UserB: id,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "You can't message this user", nil)
+			return
+		}
+	}
+	if len(others) > 1 {
+		blocked, err := cfg.db.IsBlockedAmong(r.Context(), append([]uuid.UUID{userID}, others...))
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "Some participants have blocked each other", nil)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// 3. Reuse an existing 1:1 conversation, or create a new one. The lock
+	// on the pair is held until commit, so two requests racing to start the
+	// same conversation can't both create it.
+	status := http.StatusCreated
+	var conversation database.Conversation
+	if len(others) == 1 {
+		if err := qtx.LockDirectConversation(r.Context(), directConversationKey(userID, others[0])); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't lock conversation", err)
+			return
+		}
+		conversation, err = qtx.FindDirectConversation(r.Context(), database.FindDirectConversationParams{
+			UserA: userID,
+			UserB: others[0],
+		})
+		if err != nil && err != sql.ErrNoRows {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversation", err)
+			return
+		}
+		if err == nil {
+			status = http.StatusOK
+		}
+	}
+	if status == http.StatusCreated {
+		conversation, err = qtx.CreateConversation(r.Context(), database.CreateConversationParams{
+			CreatedBy: uuid.NullUUID{UUID: userID, Valid: true},
+			IsGroup:   len(others) > 1,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create conversation", err)
+			return
+		}
+		for _, id := range append([]uuid.UUID{userID}, others...) {
+			err := qtx.AddConversationParticipant(r.Context(), database.AddConversationParticipantParams{
+				ConversationID: conversation.ID,
+				UserID:         id,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't add participant", err)
+				return
+			}
+		}
+	}
+
+	// 4. Send the opening message, if there is one
+	if params.Body != "" {
+		if _, err := sendMessage(r.Context(), qtx, conversation.ID, userID, params.Body); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+			return
+		}
+		conversation, err = qtx.GetConversationForUser(r.Context(), database.GetConversationForUserParams{
+			ID:     conversation.ID,
+			UserID: userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversation", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	results, err := cfg.conversationResponses(r.Context(), userID, []database.Conversation{conversation})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversation", err)
+		return
+	}
+	respondWithJSON(w, status, results[0])
+}
+
+// handlerConversationsGet lists the caller's conversations, most recently
+// active first
+func (cfg *apiConfig) handlerConversationsGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeMessagesRead)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	conversations, err := cfg.db.ListConversationsForUser(r.Context(), database.ListConversationsForUserParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversations", err)
+		return
+	}
+
+	results, err := cfg.conversationResponses(r.Context(), userID, conversations)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversations", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func (cfg *apiConfig) handlerConversationsGetSingle(w http.ResponseWriter, r *http.Request) {
+	userID, conversation, ok := cfg.getOwnConversation(w, r, scopeMessagesRead)
+	if !ok {
+		return
+	}
+
+	results, err := cfg.conversationResponses(r.Context(), userID, []database.Conversation{conversation})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversation", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, results[0])
+}
+
+func (cfg *apiConfig) handlerMessagesCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userID, conversation, ok := cfg.getOwnConversation(w, r, scopeMessagesWrite)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeTextBody(w, r, &params) {
+		return
+	}
+	if err := validateMessage(params.Body); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// 1. A block ends a 1:1 conversation in both directions. In groups it only
+	// hides the blocked user's messages from the blocker.
+	if !conversation.IsGroup {
+		participants, err := cfg.db.ListConversationParticipants(r.Context(), []uuid.UUID{conversation.ID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve participants", err)
+			return
+		}
+		for _, p := range participants {
+			if p.UserID == userID {
+				continue
+			}
+			blocked, err := cfg.db.IsBlockedBetween(r.Context(), database.IsBlockedBetweenParams{
+				UserA: userID,
+				UserB: p.UserID,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+				return
+			}
+			if blocked {
+				respondWithError(w, http.StatusForbidden, "You can't message this user", nil)
+				return
+			}
+		}
+	}
+
+	// 2. Store the message; there is no real-time channel, so other
+	// participants pick it up by polling with ?after=
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	message, err := sendMessage(r.Context(), qtx, conversation.ID, userID, params.Body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send message", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, messageResponse(message))
+}
+
+// sendMessage stores a message, bumps the conversation to the top of
+// everyone's list and marks it read for the sender
+func sendMessage(ctx context.Context, q *database.Queries, conversationID, senderID uuid.UUID, body string) (database.Message, error) {
+	message, err := q.CreateMessage(ctx, database.CreateMessageParams{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Body:           body,
+	})
+	if err != nil {
+		return database.Message{}, err
+	}
+	if err := q.TouchConversation(ctx, conversationID); err != nil {
+		return database.Message{}, err
+	}
+	_, err = q.MarkConversationRead(ctx, database.MarkConversationReadParams{
+		ConversationID: conversationID,
+		UserID:         senderID,
+		MessageID:      message.ID,
+	})
+	if err != nil {
+		return database.Message{}, err
+	}
+	return message, nil
+}
+
+// handlerMessagesGet pages through a conversation's messages, always
+// returned oldest first. With no cursor it returns the latest page;
+// ?before=<message_id> pages back through history and ?after=<message_id>
+// returns anything newer, which is how clients poll for new messages.
+func (cfg *apiConfig) handlerMessagesGet(w http.ResponseWriter, r *http.Request) {
+	userID, conversation, ok := cfg.getOwnConversation(w, r, scopeMessagesRead)
+	if !ok {
+		return
+	}
+
+	// 1. Parse the limit and at most one cursor
+	limit := int32(defaultMessagePageSize)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxMessagePageSize {
+			msg := fmt.Sprintf("limit must be between 1 and %d", maxMessagePageSize)
+			respondWithError(w, http.StatusBadRequest, msg, err)
+			return
+		}
+		limit = int32(n)
+	}
+	before := r.URL.Query().Get("before")
+	after := r.URL.Query().Get("after")
+	if before != "" && after != "" {
+		respondWithError(w, http.StatusBadRequest, "Use either before or after, not both", nil)
+		return
+	}
+	cursor := before + after
+	var cursorID uuid.UUID
+	if cursor != "" {
+		var err error
+		cursorID, err = uuid.Parse(cursor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid message ID", err)
+			return
+		}
+		_, err = cfg.db.GetMessageInConversation(r.Context(), database.GetMessageInConversationParams{
+			ID:             cursorID,
+			ConversationID: conversation.ID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusBadRequest, "Message isn't in this conversation", err)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve message", err)
+			return
+		}
+	}
+
+	// 2. Load the page; messages from users the caller blocked are left out
+	var messages []database.Message
+	var err error
+	switch {
+	case before != "":
+		messages, err = cfg.db.ListMessagesBefore(r.Context(), database.ListMessagesBeforeParams{
+			ConversationID: conversation.ID,
+			ViewerID:       userID,
+			CursorID:       cursorID,
+			MaxResults:     limit,
+		})
+	case after != "":
+		messages, err = cfg.db.ListMessagesAfter(r.Context(), database.ListMessagesAfterParams{
+			ConversationID: conversation.ID,
+			ViewerID:       userID,
+			CursorID:       cursorID,
+			MaxResults:     limit,
+		})
+	default:
+		messages, err = cfg.db.ListLatestMessages(r.Context(), database.ListLatestMessagesParams{
+			ConversationID: conversation.ID,
+			ViewerID:       userID,
+			MaxResults:     limit,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve messages", err)
+		return
+	}
+
+	// 3. Backwards pages come newest first from the database
+	results := make([]Message, len(messages))
+	for i, message := range messages {
+		if after != "" {
+			results[i] = messageResponse(message)
+		} else {
+			results[len(messages)-1-i] = messageResponse(message)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// handlerConversationsRead records a read receipt. Receipts only move
+// forward: marking an older message read leaves the receipt where it is.
+func (cfg *apiConfig) handlerConversationsRead(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MessageID uuid.UUID `json:"message_id"`
+	}
+
+	userID, conversation, ok := cfg.getOwnConversation(w, r, scopeMessagesWrite)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeTextBody(w, r, &params) {
+		return
+	}
+
+	_, err := cfg.db.GetMessageInConversation(r.Context(), database.GetMessageInConversationParams{
+		ID:             params.MessageID,
+		ConversationID: conversation.ID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Message not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve message", err)
+		return
+	}
+
+	_, err = cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+		MessageID:      params.MessageID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't mark conversation read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getOwnConversation authenticates the caller and loads the conversation in
+// the path, responding with 404 unless the caller is a participant
+func (cfg *apiConfig) getOwnConversation(w http.ResponseWriter, r *http.Request, scope string) (uuid.UUID, database.Conversation, bool) {
+	userID, ok := cfg.authenticate(w, r, scope)
+	if !ok {
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid conversation ID", err)
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	conversation, err := cfg.db.GetConversationForUser(r.Context(), database.GetConversationForUserParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Conversation not found", err)
+			return uuid.Nil, database.Conversation{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve conversation", err)
+		return uuid.Nil, database.Conversation{}, false
+	}
+	return userID, conversation, true
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	oauthCodeTTL         = 10 * time.Minute
+	oauthAccessTokenTTL  = time.Hour
+	oauthRefreshTokenTTL = 60 * 24 * time.Hour
+)
+
+var scopeDescriptions = map[string]string{
+	scopeChirpsRead:    "Read chirps on your behalf",
+	scopeChirpsWrite:   "Post and delete chirps as you",
+	scopeMessagesRead:  "Read your direct messages",
+	scopeMessagesWrite: "Send direct messages as you",
+	scopeProfileRead:   "See your account details and subscription",
+	scopeProfileWrite:  "Change your email address and password",
+	scopeWebhooks:      "Send notifications about your activity to its own servers",
+	scopeBlocksRead:    "See who you've blocked",
+	scopeBlocksWrite:   "Block and unblock users as you",
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+
+<head>
+	<title>Authorize {{.ClientName}} - Chirpy</title>
+</head>
+
+<body>
+	<h1>Authorize {{.ClientName}}</h1>
+	<p><strong>{{.ClientName}}</strong> would like to:</p>
+	<ul>
+		{{range .Scopes}}<li>{{.}}</li>{{end}}
+	</ul>
+	{{if .Error}}<p style="color: red">{{.Error}}</p>{{end}}
+	<form method="POST" action="/oauth/authorize">
+		<input type="hidden" name="response_type" value="code">
+		<input type="hidden" name="client_id" value="{{.ClientID}}">
+		<input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+		<input type="hidden" name="scope" value="{{.Scope}}">
+		<input type="hidden" name="state" value="{{.State}}">
+		<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+		<input type="hidden" name="code_challenge_method" value="S256">
+		<p><label>Email <input type="email" name="email" value="{{.Email}}" required></label></p>
+		<p><label>Password <input type="password" name="password" required></label></p>
+		<p><label>Two-factor code (if enabled) <input type="text" name="code" autocomplete="one-time-code"></label></p>
+		<button type="submit" name="action" value="approve">Allow</button>
+		<button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+	</form>
+</body>
+
+</html>
+`))
+
+type oauthAuthorizeRequest struct {
+	client        database.OauthClient
+	redirectURI   string
+	scopes        []string
+	state         string
+	codeChallenge string
+}
+
+// oauthError is an RFC 6749 error. Errors found before the redirect URI has
+// been validated are shown to the user instead of being redirected.
+type oauthError struct {
+	code        string
+	description string
+}
+
+// parseAuthorizeRequest validates an authorization request from the query
+// string (GET) or the consent form (POST)
+func (cfg *apiConfig) parseAuthorizeRequest(r *http.Request) (oauthAuthorizeRequest, *oauthError) {
+	req := oauthAuthorizeRequest{
+		state: r.FormValue("state"),
+	}
+
+	// 1. Client and redirect URI must match a registration before we redirect anywhere
+	clientID, err := uuid.Parse(r.FormValue("client_id"))
+	if err != nil {
+		return req, &oauthError{"invalid_request", "Invalid client_id"}
+	}
+	req.client, err = cfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return req, &oauthError{"invalid_request", "Unknown client"}
+	}
+	redirectURI := r.FormValue("redirect_uri")
+	if !slices.Contains(req.client.RedirectUris, redirectURI) {
+		return req, &oauthError{"invalid_request", "redirect_uri doesn't match the client registration"}
+	}
+	req.redirectURI = redirectURI
+
+	// 2. From here on errors go back to the client
+	if r.FormValue("response_type") != "code" {
+		return req, &oauthError{"unsupported_response_type", "Only the authorization code flow is supported"}
+	}
+	req.codeChallenge = r.FormValue("code_challenge")
+	if req.codeChallenge == "" || r.FormValue("code_challenge_method") != "S256" {
+		return req, &oauthError{"invalid_request", "PKCE with code_challenge_method=S256 is required"}
+	}
+	req.scopes = strings.Fields(r.FormValue("scope"))
+	if len(req.scopes) == 0 {
+		return req, &oauthError{"invalid_scope", "At least one scope is required"}
+	}
+	for _, scope := range req.scopes {
+		if !slices.Contains(validScopes, scope) {
+			return req, &oauthError{"invalid_scope", "Unknown scope: " + scope}
+		}
+	}
+
+	return req, nil
+}
+
+func (cfg *apiConfig) handlerOAuthAuthorizeGet(w http.ResponseWriter, r *http.Request) {
+	req, oauthErr := cfg.parseAuthorizeRequest(r)
+	if oauthErr != nil {
+		respondWithAuthorizeError(w, r, req, oauthErr)
+		return
+	}
+
+	renderConsent(w, http.StatusOK, req, "", "")
+}
+
+func (cfg *apiConfig) handlerOAuthAuthorizePost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	req, oauthErr := cfg.parseAuthorizeRequest(r)
+	if oauthErr != nil {
+		respondWithAuthorizeError(w, r, req, oauthErr)
+		return
+	}
+
+	if r.FormValue("action") != "approve" {
+		respondWithAuthorizeError(w, r, req, &oauthError{"access_denied", "The user denied the request"})
+		return
+	}
+
+	// 1. Log the user in, with the same lockout rules as POST /api/login
+	email := r.FormValue("email")
+	accountKey := "email:" + strings.ToLower(email)
+	ipKey := "ip:" + clientIP(r)
+	for _, key := range []string{accountKey, ipKey} {
+		retryAfter, err := cfg.loginRetryAfter(r.Context(), key)
+		if err != nil {
+			http.Error(w, "Couldn't check login attempts", http.StatusInternalServerError)
+			return
+		}
+		if retryAfter > 0 {
+			renderConsent(w, http.StatusTooManyRequests, req, email, "Too many failed login attempts, try again later")
+			return
+		}
+	}
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		auth.CheckDummyPasswordHash(r.FormValue("password"))
+		cfg.recordLoginFailure(r, accountKey, ipKey, nil)
+		renderConsent(w, http.StatusUnauthorized, req, email, "Incorrect email or password")
+		return
+	}
+	match, err := auth.CheckPasswordHash(r.FormValue("password"), user.HashedPassword)
+	if err != nil || !match {
+		cfg.recordLoginFailure(r, accountKey, ipKey, &user)
+		renderConsent(w, http.StatusUnauthorized, req, email, "Incorrect email or password")
+		return
+	}
+
+	// 2. Accounts with 2FA need a code too
+	totp, err := cfg.db.GetUserTOTP(r.Context(), user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Couldn't check two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		ok, err := cfg.checkSecondFactor(r, user.ID, r.FormValue("code"), "")
+		if err != nil {
+			http.Error(w, "Couldn't check two-factor code", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			cfg.recordLoginFailure(r, accountKey, ipKey, &user)
+			renderConsent(w, http.StatusUnauthorized, req, email, "Enter a valid two-factor code")
+			return
+		}
+	}
+
+	if err := cfg.db.ClearLoginFailures(r.Context(), accountKey); err != nil {
+		log.Printf("Couldn't clear login failures: %s", err)
+	}
+
+	// 3. Hand the client a single-use code bound to its PKCE challenge
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		http.Error(w, "Couldn't create authorization code", http.StatusInternalServerError)
+		return
+	}
+	err = cfg.db.CreateOAuthAuthorizationCode(r.Context(), database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.client.ID,
+		UserID:        user.ID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        req.scopes,
+		CodeChallenge: req.codeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(oauthCodeTTL),
+	})
+	if err != nil {
+		http.Error(w, "Couldn't save authorization code", http.StatusInternalServerError)
+		return
+	}
+
+	redirectWithParams(w, r, req.redirectURI, url.Values{
+		"code":  {code},
+		"state": {req.state},
+	})
+}
+
+func renderConsent(w http.ResponseWriter, code int, req oauthAuthorizeRequest, email, errMsg string) {
+	scopes := []string{}
+	for _, scope := range req.scopes {
+		scopes = append(scopes, scopeDescriptions[scope])
+	}
+
+	// Keep the consent screen out of other sites' frames
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	err := consentTemplate.Execute(w, struct {
+		ClientName    string
+		ClientID      uuid.UUID
+		RedirectURI   string
+		Scope         string
+		Scopes        []string
+		State         string
+		CodeChallenge string
+		Email         string
+		Error         string
+	}{
+		ClientName:    req.client.Name,
+		ClientID:      req.client.ID,
+		RedirectURI:   req.redirectURI,
+		Scope:         strings.Join(req.scopes, " "),
+		Scopes:        scopes,
+		State:         req.state,
+		CodeChallenge: req.codeChallenge,
+		Email:         email,
+		Error:         errMsg,
+	})
+	if err != nil {
+		log.Printf("Error rendering consent screen: %s", err)
+	}
+}
+
+func respondWithAuthorizeError(w http.ResponseWriter, r *http.Request, req oauthAuthorizeRequest, oauthErr *oauthError) {
+	if req.redirectURI == "" {
+		http.Error(w, oauthErr.description, http.StatusBadRequest)
+		return
+	}
+	redirectWithParams(w, r, req.redirectURI, url.Values{
+		"error":             {oauthErr.code},
+		"error_description": {oauthErr.description},
+		"state":             {req.state},
+	})
+}
+
+func redirectWithParams(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "Invalid redirect URI", http.StatusBadRequest)
+		return
+	}
+	query := u.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "Couldn't parse form")
+		return
+	}
+
+	client, ok := cfg.authenticateOAuthClient(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		// 1. Codes are single use and bound to the client, redirect URI and PKCE challenge
+		authCode, err := cfg.db.UseOAuthAuthorizationCode(r.Context(), auth.HashToken(r.PostFormValue("code")))
+		if err != nil {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+			return
+		}
+		if authCode.ClientID != client.ID || authCode.RedirectUri != r.PostFormValue("redirect_uri") {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Authorization code was issued to another client")
+			return
+		}
+		if !auth.VerifyPKCE(r.PostFormValue("code_verifier"), authCode.CodeChallenge) {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid code_verifier")
+			return
+		}
+
+		cfg.respondWithOAuthTokens(w, r, client, authCode.UserID, authCode.Scopes)
+
+	case "refresh_token":
+		// 2. Refresh tokens rotate: spending one revokes it in the same statement
+		refreshToken, err := cfg.db.UseOAuthRefreshToken(r.Context(), database.UseOAuthRefreshTokenParams{
+			Token:    r.PostFormValue("refresh_token"),
+			ClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+		})
+		if err != nil {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired refresh token")
+			return
+		}
+
+		cfg.respondWithOAuthTokens(w, r, client, refreshToken.UserID, refreshToken.Scopes)
+
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant_type")
+	}
+}
+
+func (cfg *apiConfig) respondWithOAuthTokens(w http.ResponseWriter, r *http.Request, client database.OauthClient, userID uuid.UUID, scopes []string) {
+	type response struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+
+	accessToken, err := auth.MakeScopedJWT(userID, cfg.jwtSecret, oauthAccessTokenTTL, client.ID, scopes)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't create access token")
+		return
+	}
+
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't create refresh token")
+		return
+	}
+	_, err = cfg.db.CreateOAuthRefreshToken(r.Context(), database.CreateOAuthRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(oauthRefreshTokenTTL),
+		ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+		Scopes:    scopes,
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't save refresh token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, response{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+func (cfg *apiConfig) handlerOAuthRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "Couldn't parse form")
+		return
+	}
+
+	client, ok := cfg.authenticateOAuthClient(w, r)
+	if !ok {
+		return
+	}
+
+	// Access tokens are stateless JWTs and simply expire; refresh tokens are revoked.
+	// Unknown tokens are not an error (RFC 7009).
+	err := cfg.db.RevokeOAuthRefreshToken(r.Context(), database.RevokeOAuthRefreshTokenParams{
+		Token:    r.PostFormValue("token"),
+		ClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't revoke token")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (cfg *apiConfig) handlerOAuthIntrospect(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Sub       string `json:"sub,omitempty"`
+		Exp       int64  `json:"exp,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+	}
+
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "Couldn't parse form")
+		return
+	}
+
+	client, ok := cfg.authenticateOAuthClient(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostFormValue("token")
+
+	// Clients can only introspect tokens that were issued to them
+	access, err := auth.ParseAccessToken(token, cfg.jwtSecret)
+	if err == nil && access.ClientID == client.ID {
+		respondWithJSON(w, http.StatusOK, response{
+			Active:    true,
+			Scope:     strings.Join(access.Scopes, " "),
+			ClientID:  client.ID.String(),
+			Sub:       access.UserID.String(),
+			Exp:       access.ExpiresAt.Unix(),
+			TokenType: "access_token",
+		})
+		return
+	}
+
+	refreshToken, err := cfg.db.GetOAuthRefreshToken(r.Context(), database.GetOAuthRefreshTokenParams{
+		Token:    token,
+		ClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+	})
+	if err == nil {
+		respondWithJSON(w, http.StatusOK, response{
+			Active:    true,
+			Scope:     strings.Join(refreshToken.Scopes, " "),
+			ClientID:  client.ID.String(),
+			Sub:       refreshToken.UserID.String(),
+			Exp:       refreshToken.ExpiresAt.Unix(),
+			TokenType: "refresh_token",
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{Active: false})
+}
+
+// authenticateOAuthClient identifies the client from HTTP Basic auth or the
+// form body. Public clients only send their ID and rely on PKCE.
+func (cfg *apiConfig) authenticateOAuthClient(w http.ResponseWriter, r *http.Request) (database.OauthClient, bool) {
+	clientIDStr, secret, hasBasic := r.BasicAuth()
+	if !hasBasic {
+		clientIDStr = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	clientID, err := uuid.Parse(clientIDStr)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Invalid client_id")
+		return database.OauthClient{}, false
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Unknown client")
+		return database.OauthClient{}, false
+	}
+
+	if client.SecretHash.Valid {
+		given := auth.HashToken(secret)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(client.SecretHash.String)) != 1 {
+			respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Invalid client credentials")
+			return database.OauthClient{}, false
+		}
+	}
+
+	return client, true
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code int, oauthErr, description string) {
+	type errorResponse struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, errorResponse{
+		Error:            oauthErr,
+		ErrorDescription: description,
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+)
+
+const (
+	passwordResetTTL = 30 * time.Minute
+	// passwordForgotMinDuration is comfortably longer than looking up the
+	// user and queueing the email, which only happens for known addresses
+	passwordForgotMinDuration = 500 * time.Millisecond
+)
+
+func (cfg *apiConfig) handlerPasswordForgot(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	// Always answer the same way, and no sooner than passwordForgotMinDuration,
+	// so neither the status nor the response time reveals whether the email
+	// exists
+	start := time.Now()
+	cfg.sendPasswordResetEmail(r.Context(), params.Email)
+	if wait := passwordForgotMinDuration - time.Since(start); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-r.Context().Done():
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *apiConfig) sendPasswordResetEmail(ctx context.Context, email string) {
+	user, err := cfg.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Couldn't look up user for password reset: %s", err)
+		}
+		return
+	}
+
+	token, err := auth.MakeSignedToken(cfg.jwtSecret, "password-reset")
+	if err != nil {
+		log.Printf("Couldn't create password reset token: %s", err)
+		return
+	}
+
+	_, err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	})
+	if err != nil {
+		log.Printf("Couldn't save password reset token: %s", err)
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Someone asked to reset the password for your Chirpy account.\n\n"+
+			"Use this token with POST /api/password/reset within %s:\n\n%s\n\n"+
+			"If this wasn't you, you can ignore this email.\n",
+		passwordResetTTL, token,
+	)
+	if err := cfg.enqueueEmail(ctx, user.Email, "Reset your Chirpy password", body); err != nil {
+		log.Printf("Couldn't queue password reset email: %s", err)
+	}
+}
+
+func (cfg *apiConfig) handlerPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Password is required", nil)
+		return
+	}
+
+	// 1. Reject forged tokens before touching the database
+	if err := auth.VerifySignedToken(params.Token, cfg.jwtSecret, "password-reset"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token", err)
+		return
+	}
+
+	// 2. Hash the new password before touching the token, so a failure here
+	// doesn't burn it
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+		return
+	}
+
+	// 3. Consume the token and set the password together, so the token is
+	// only used up if the password actually changes
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// The token only works once and before it expires
+	reset, err := qtx.UsePasswordResetToken(r.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "Invalid or expired token", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify token", err)
+		return
+	}
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             reset.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update password", err)
+		return
+	}
+
+	// 4. Log out every session and burn any other outstanding reset links
+	if err := qtx.RevokeAllRefreshTokensForUser(r.Context(), reset.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	if err := qtx.InvalidatePasswordResetTokens(r.Context(), reset.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't invalidate reset tokens", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	pollMinOptions      = 2
+	pollMaxOptions      = 4
+	pollMaxOptionLength = 25
+	pollMinDuration     = 5 * time.Minute
+	pollMaxDuration     = 7 * 24 * time.Hour
+)
+
+// pollParameters is the poll part of POST /api/chirps
+type pollParameters struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+	// Results are hidden until the viewer votes or the poll closes unless
+	// the author turns this off
+	HideResults *bool `json:"hide_results"`
+}
+
+func (p *pollParameters) validate(now time.Time) error {
+	if len(p.Options) < pollMinOptions || len(p.Options) > pollMaxOptions {
+		return fmt.Errorf("A poll needs %d to %d options", pollMinOptions, pollMaxOptions)
+	}
+	seen := map[string]bool{}
+	for i, option := range p.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return errors.New("Poll options can't be empty")
+		}
+		if utf8.RuneCountInString(option) > pollMaxOptionLength {
+			return fmt.Errorf("Poll options can be at most %d characters", pollMaxOptionLength)
+		}
+		if seen[strings.ToLower(option)] {
+			return errors.New("Poll options must be different")
+		}
+		seen[strings.ToLower(option)] = true
+		p.Options[i] = option
+	}
+
+	duration := p.ClosesAt.Sub(now)
+	if duration < pollMinDuration || duration > pollMaxDuration {
+		return fmt.Errorf("A poll must close between %s and %s from now", pollMinDuration, pollMaxDuration)
+	}
+	return nil
+}
+
+func createPoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, params pollParameters) error {
+	hideResults := true
+	if params.HideResults != nil {
+		hideResults = *params.HideResults
+	}
+
+	poll, err := q.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:     chirpID,
+		ClosesAt:    params.ClosesAt.UTC(),
+		HideResults: hideResults,
+	})
+	if err != nil {
+		return err
+	}
+	for i, option := range params.Options {
+		_, err := q.CreatePollOption(ctx, database.CreatePollOptionParams{
+			PollID:   poll.ID,
+			Position: int32(i),
+			Text:     option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerPollVote(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+
+	// 1. Authenticate
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	// 2. Find the poll and check it's still open and the option belongs to it.
+	// Polls on chirps the voter can't see don't exist as far as they know.
+	chirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Poll not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+	poll, err := cfg.db.GetPollByChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Poll not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve poll", err)
+		return
+	}
+	if !time.Now().UTC().Before(poll.ClosesAt) {
+		respondWithError(w, http.StatusConflict, "Poll is closed", nil)
+		return
+	}
+
+	options, err := cfg.db.GetPollOptionTallies(r.Context(), []uuid.UUID{poll.ID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve poll", err)
+		return
+	}
+	valid := false
+	for _, option := range options {
+		if option.ID == params.OptionID {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		respondWithError(w, http.StatusBadRequest, "Invalid option ID", nil)
+		return
+	}
+
+	// 3. One vote per user; votes can't be changed
+	recorded, err := cfg.db.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		PollID:   poll.ID,
+		UserID:   userID,
+		OptionID: params.OptionID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record vote", err)
+		return
+	}
+	if recorded == 0 {
+		respondWithError(w, http.StatusConflict, "You have already voted in this poll", nil)
+		return
+	}
+
+	// 4. Respond with the poll, now including the tallies
+	polls, err := cfg.pollResponses(r.Context(), userID, []database.Chirp{chirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve poll", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, polls[chirpID])
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/activitypub"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// RemoteFollow is a fediverse account the caller follows. It's pending until
+// the remote server accepts the follow.
+type RemoteFollow struct {
+	ActorID   uuid.UUID `json:"actor_id"`
+	ActorURL  string    `json:"actor_url"`
+	Username  string    `json:"username"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RemoteNote struct {
+	ID          uuid.UUID `json:"id"`
+	ActorURL    string    `json:"actor_url"`
+	Username    string    `json:"username"`
+	URL         string    `json:"url"`
+	Content     string    `json:"content"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// handlerRemoteFollowsCreate follows an account on another server, given as
+// "user@host". The follow is answered asynchronously, with 202 until then.
+func (cfg *apiConfig) handlerRemoteFollowsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Account string `json:"account"`
+	}
+
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	// 1. Resolve the account to its actor, signed as the caller for servers
+	// that only serve actors to signed requests
+	actorURL, err := cfg.federation.WebFinger(r.Context(), params.Account)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't find that account", err)
+		return
+	}
+	signer, err := cfg.actorSigner(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve actor key", err)
+		return
+	}
+	actor, err := cfg.federation.FetchActor(r.Context(), actorURL, &signer)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Couldn't fetch that account", err)
+		return
+	}
+	remote, err := cfg.saveRemoteActor(r.Context(), actor)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't follow that account", err)
+		return
+	}
+
+	// 2. Record the follow and queue it together. Following twice is a
+	// no-op.
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	follow, err := activitypub.NewActivity(cfg.newActivityID(), "Follow", cfg.actorURL(userID), remote.ActorUrl)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create follow", err)
+		return
+	}
+	created, err := qtx.CreateRemoteFollowing(r.Context(), database.CreateRemoteFollowingParams{
+		UserID:        userID,
+		RemoteActorID: remote.ID,
+		ActivityID:    follow.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow account", err)
+		return
+	}
+	if created > 0 {
+		if err := enqueueActivity(r.Context(), qtx, userID, follow, []string{remote.InboxUrl}); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't queue follow", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *apiConfig) handlerRemoteFollowsGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	follows, err := cfg.db.ListRemoteFollowing(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve followed accounts", err)
+		return
+	}
+
+	results := []RemoteFollow{}
+	for _, follow := range follows {
+		results = append(results, RemoteFollow{
+			ActorID:   follow.RemoteActorID,
+			ActorURL:  follow.ActorUrl,
+			Username:  follow.Username,
+			Accepted:  follow.AcceptedAt.Valid,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// handlerRemoteFollowsDelete unfollows a remote account, telling its server
+// by undoing the original follow
+func (cfg *apiConfig) handlerRemoteFollowsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	actorID, err := uuid.Parse(r.PathValue("actorID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid actor ID", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	follow, err := qtx.DeleteRemoteFollowing(r.Context(), database.DeleteRemoteFollowingParams{
+		UserID:        userID,
+		RemoteActorID: actorID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "You don't follow this account", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow account", err)
+		return
+	}
+	remote, err := qtx.GetRemoteActor(r.Context(), actorID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve account", err)
+		return
+	}
+
+	actorURL := cfg.actorURL(userID)
+	undone, err := activitypub.NewActivity(follow.ActivityID, "Follow", actorURL, remote.ActorUrl)
+	if err == nil {
+		undone.Context = nil
+		var undo activitypub.Activity
+		undo, err = activitypub.NewActivity(follow.ActivityID+"#undo", "Undo", actorURL, undone)
+		if err == nil {
+			err = enqueueActivity(r.Context(), qtx, userID, undo, []string{remote.InboxUrl})
+		}
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't queue unfollow", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't commit transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerRemoteNotesGet lists notes from accepted remote follows, newest
+// first. Content is plain text: remote HTML is stripped on arrival.
+func (cfg *apiConfig) handlerRemoteNotesGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	notes, err := cfg.db.ListRemoteNotesForUser(r.Context(), database.ListRemoteNotesForUserParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve notes", err)
+		return
+	}
+
+	results := []RemoteNote{}
+	for _, note := range notes {
+		results = append(results, RemoteNote{
+			ID:          note.ID,
+			ActorURL:    note.ActorUrl,
+			Username:    note.Username,
+			URL:         note.Url,
+			Content:     note.Content,
+			PublishedAt: note.PublishedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+)
+
+type SubscriptionEvent struct {
+	Event     string    `json:"event"`
+	Status    string    `json:"status"`
+	PeriodEnd time.Time `json:"period_end"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Subscription struct {
+	Status             string              `json:"status"`
+	CurrentPeriodStart time.Time           `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time           `json:"current_period_end"`
+	IsChirpyRed        bool                `json:"is_chirpy_red"`
+	History            []SubscriptionEvent `json:"history"`
+}
+
+func (cfg *apiConfig) handlerSubscriptionGet(w http.ResponseWriter, r *http.Request) {
+	// 1. Authenticate
+	userID, ok := cfg.authenticate(w, r, scopeProfileRead)
+	if !ok {
+		return
+	}
+
+	// 2. Look up the subscription; users who never subscribed have none
+	sub, err := cfg.db.GetSubscriptionByUser(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "No subscription found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve subscription", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	// 3. Attach the status history, newest first
+	events, err := cfg.db.ListSubscriptionEvents(r.Context(), sub.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve subscription history", err)
+		return
+	}
+
+	history := []SubscriptionEvent{}
+	for _, event := range events {
+		history = append(history, SubscriptionEvent{
+			Event:     event.Event,
+			Status:    event.Status,
+			PeriodEnd: event.PeriodEnd,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, Subscription{
+		Status:             sub.Status,
+		CurrentPeriodStart: sub.CurrentPeriodStart,
+		CurrentPeriodEnd:   sub.CurrentPeriodEnd,
+		IsChirpyRed:        user.IsChirpyRed,
+		History:            history,
+	})
+}
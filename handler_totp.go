@@ -0,0 +1,204 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+)
+
+const recoveryCodeCount = 10
+
+func (cfg *apiConfig) handlerTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret string `json:"secret"`
+		URI    string `json:"otpauth_uri"`
+	}
+
+	// 1. Session only: a token mustn't be able to set up 2FA on the account
+	userID, ok := cfg.authenticate(w, r, scopeSession)
+	if !ok {
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	// 2. Refuse to silently replace an active authenticator
+	existing, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor authentication", err)
+		return
+	}
+	if err == nil && existing.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	// 3. Store a fresh, unconfirmed secret
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate secret", err)
+		return
+	}
+	_, err = cfg.db.UpsertUserTOTP(r.Context(), database.UpsertUserTOTPParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save secret", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{
+		Secret: secret,
+		URI:    auth.TOTPURI(secret, "Chirpy", user.Email),
+	})
+}
+
+func (cfg *apiConfig) handlerTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	// 1. Session only, like enrolling
+	userID, ok := cfg.authenticate(w, r, scopeSession)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Two-factor enrollment not started", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve two-factor secret", err)
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	// 2. The first code proves the authenticator app was set up correctly
+	step, ok := auth.ValidateTOTP(params.Code, totp.Secret, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid two-factor code", nil)
+		return
+	}
+	_, err = cfg.db.SetTOTPLastUsedStep(r.Context(), database.SetTOTPLastUsedStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't confirm two-factor authentication", err)
+		return
+	}
+
+	// 3. Replace any old recovery codes; they're only shown this once
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate recovery codes", err)
+		return
+	}
+	if err := cfg.db.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save recovery codes", err)
+		return
+	}
+	for _, code := range codes {
+		err := cfg.db.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(code),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't save recovery codes", err)
+			return
+		}
+	}
+
+	if err := cfg.db.ConfirmUserTOTP(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't confirm two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		RecoveryCodes: codes,
+	})
+}
+
+func (cfg *apiConfig) handlerTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	// 1. Session only: a token mustn't be able to turn 2FA off
+	userID, ok := cfg.authenticate(w, r, scopeSession)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	// 2. A stolen access token alone isn't enough to turn 2FA off. Wrong
+	// codes count towards the login lockout, so the code can't be guessed.
+	user, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+	accountKey := "email:" + strings.ToLower(user.Email)
+	ipKey := "ip:" + clientIP(r)
+	if !cfg.checkLoginLockout(w, r, accountKey, ipKey) {
+		return
+	}
+
+	ok, err = cfg.checkSecondFactor(r, userID, params.Code, params.RecoveryCode)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor code", err)
+		return
+	}
+	if !ok {
+		cfg.recordLoginFailure(r, accountKey, ipKey, &user)
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code", nil)
+		return
+	}
+	if err := cfg.db.ClearLoginFailures(r.Context(), accountKey); err != nil {
+		log.Printf("Couldn't clear login failures: %s", err)
+	}
+
+	if err := cfg.db.DeleteUserTOTP(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	if err := cfg.db.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete recovery codes", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
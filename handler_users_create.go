@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"net/mail"
 	"time"
 
 	"workspace/github.com/kozykoding/chirpy/internal/auth"
@@ -12,11 +14,13 @@ import (
 )
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	EmailVerified bool      `json:"email_verified"`
+	IsPrivate     bool      `json:"is_private"`
 }
 
 func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request) {
@@ -36,6 +40,11 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if _, err := mail.ParseAddress(params.Email); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address", err)
+		return
+	}
+
 	// 1. Hash the password using the internal/auth package
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
@@ -53,7 +62,12 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// 3. Respond without the hashed password
+	// 3. Send the verification link; the account works without it, so don't fail signup
+	if err := cfg.sendVerificationEmail(r.Context(), user, verifySignup); err != nil {
+		log.Printf("Couldn't send verification email: %s", err)
+	}
+
+	// 4. Respond without the hashed password
 	respondWithJSON(w, http.StatusCreated, response{
 		User: User{
 			ID:        user.ID,
@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"net/mail"
 
 	"workspace/github.com/kozykoding/chirpy/internal/auth"
 	"workspace/github.com/kozykoding/chirpy/internal/database"
@@ -14,16 +16,9 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 		Email    string `json:"email"`
 	}
 
-	// 1. Authenticate via Access Token (JWT)
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+	// 1. Authenticate; tokens need profile:write
+	userID, ok := cfg.authenticate(w, r, scopeProfileWrite)
+	if !ok {
 		return
 	}
 
@@ -35,6 +30,17 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if _, err := mail.ParseAddress(params.Email); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address", err)
+		return
+	}
+
+	previous, err := cfg.db.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
 	// 3. Hash the New Password
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
@@ -53,11 +59,21 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// 5. Respond with the Updated User Resource
+	// 5. A new email address has to be verified again
+	if user.Email != previous.Email {
+		if err := cfg.sendVerificationEmail(r.Context(), user, verifyEmailChange); err != nil {
+			log.Printf("Couldn't send verification email: %s", err)
+		}
+	}
+
+	// 6. Respond with the Updated User Resource
 	respondWithJSON(w, http.StatusOK, User{
-		ID:        user.ID,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Email:     user.Email,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		IsChirpyRed:   user.IsChirpyRed,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		IsPrivate:     user.IsPrivate,
 	})
 }
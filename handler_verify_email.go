@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing token", nil)
+		return
+	}
+
+	// 1. Reject forged tokens before touching the database
+	if err := auth.VerifySignedToken(token, cfg.jwtSecret, "verify-email"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token", err)
+		return
+	}
+
+	// 2. Consume the token; it only works once and before it expires
+	verification, err := cfg.db.UseEmailVerificationToken(r.Context(), auth.HashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "Invalid or expired token", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify token", err)
+		return
+	}
+
+	// 3. Only verify the address the token was sent to
+	user, err := cfg.db.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "Email has changed since this link was sent", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		IsChirpyRed:   user.IsChirpyRed,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		IsPrivate:     user.IsPrivate,
+	})
+}
@@ -1,56 +1,236 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
-	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/webhook"
 
 	"github.com/google/uuid"
 )
 
+const (
+	polkaTimestampHeader = "X-Polka-Timestamp"
+	polkaSignatureHeader = "X-Polka-Signature"
+	polkaTolerance       = 5 * time.Minute
+	polkaMaxBodyBytes    = 1 << 20
+	// rejectedWebhookLogBytes is how much of a rejected delivery's body and
+	// of each of its headers is logged
+	rejectedWebhookLogBytes = 256
+)
+
+// rejectedWebhookHeaders are the only headers logged for rejected deliveries
+var rejectedWebhookHeaders = []string{"Content-Type", "User-Agent", polkaTimestampHeader, polkaSignatureHeader}
+
+// Outcomes stored in the inbound webhook log. Rows that never got one (e.g.
+// the server died mid-delivery) stay "received".
+const (
+	webhookRejected  = "rejected"
+	webhookInvalid   = "invalid"
+	webhookProcessed = "processed"
+	webhookDuplicate = "duplicate"
+	webhookFailed    = "failed"
+)
+
+// polkaEvent is the payload Polka sends for every subscription change
+type polkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID      uuid.UUID  `json:"user_id"`
+		PeriodStart *time.Time `json:"period_start"`
+		PeriodEnd   *time.Time `json:"period_end"`
+	} `json:"data"`
+}
+
+// webhookResult is how a verified Polka delivery was handled
+type webhookResult struct {
+	outcome string
+	code    int
+	msg     string // error message for the sender, empty on success
+	err     error
+	eventID string
+	event   string
+}
+
 func (cfg *apiConfig) handlerWebhook(w http.ResponseWriter, r *http.Request) {
-	apiKey, err := auth.GetAPIKey(r.Header)
+	// 1. Read the raw body; the signature covers the exact bytes
+	body, err := io.ReadAll(io.LimitReader(r.Body, polkaMaxBodyBytes))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't retrieve api key", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't read body", err)
 		return
 	}
 
-	if apiKey != cfg.polkaKey {
-		respondWithError(w, http.StatusUnauthorized, "Invalid api key", nil)
+	// 2. Verify the HMAC against every active key, within the tolerance window
+	verifyErr := webhook.Verify(
+		cfg.polkaKeys,
+		r.Header.Get(polkaTimestampHeader),
+		r.Header.Get(polkaSignatureHeader),
+		body,
+		polkaTolerance,
+		time.Now(),
+	)
+
+	// 3. Log the delivery so failures can be inspected and replayed later.
+	// Anyone can post here, so a rejected delivery only keeps enough to
+	// explain the rejection.
+	if verifyErr != nil {
+		headers, prefix := rejectedWebhookLog(r.Header, body)
+		logID := cfg.logInboundWebhook(r.Context(), headers, prefix, uuid.NullUUID{})
+		res := webhookResult{
+			outcome: webhookRejected,
+			code:    http.StatusUnauthorized,
+			msg:     "Invalid signature",
+			err:     verifyErr,
+		}
+		cfg.finishInboundWebhook(r.Context(), logID, false, verifyErr, res)
+		respondWithWebhookResult(w, res)
 		return
 	}
+	logID := cfg.logInboundWebhook(r.Context(), r.Header, body, uuid.NullUUID{})
+
+	// 4. Apply the event and record what happened
+	res := cfg.processPolkaWebhook(r.Context(), body)
+	cfg.finishInboundWebhook(r.Context(), logID, true, nil, res)
+	respondWithWebhookResult(w, res)
+}
+
+// processPolkaWebhook applies a verified Polka delivery. It is shared by the
+// webhook endpoint and admin replays.
+func (cfg *apiConfig) processPolkaWebhook(ctx context.Context, body []byte) webhookResult {
+	params := polkaEvent{}
+	err := json.Unmarshal(body, &params)
+	if err != nil {
+		return webhookResult{outcome: webhookInvalid, code: http.StatusBadRequest, msg: "Couldn't decode parameters", err: err}
+	}
+	if params.ID == "" {
+		return webhookResult{outcome: webhookInvalid, code: http.StatusBadRequest, msg: "Missing event ID", event: params.Event}
+	}
 
-	type parameters struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID uuid.UUID `json:"user_id"`
-		} `json:"data"`
+	res := webhookResult{eventID: params.ID, event: params.Event}
+	fail := func(code int, msg string, err error) webhookResult {
+		res.outcome = webhookFailed
+		res.code = code
+		res.msg = msg
+		res.err = err
+		return res
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
+	// Record the event ID and apply it in one transaction, so a duplicate
+	// delivery is a no-op and a failed one can be retried
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
-		return
+		return fail(http.StatusInternalServerError, "Couldn't start transaction", err)
 	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-	if params.Event != "user.upgraded" {
-		respondWithJSON(w, http.StatusNoContent, struct{}{})
-		return
+	recorded, err := qtx.RecordWebhookEvent(ctx, database.RecordWebhookEventParams{
+		EventID: params.ID,
+		Event:   params.Event,
+	})
+	if err != nil {
+		return fail(http.StatusInternalServerError, "Couldn't record event", err)
+	}
+	if recorded == 0 {
+		res.outcome = webhookDuplicate
+		res.code = http.StatusNoContent
+		return res
 	}
 
-	_, err = cfg.db.UpgradeUserToChirpyRed(r.Context(), params.Data.UserID)
+	err = applySubscriptionEvent(ctx, qtx, params, time.Now().UTC())
 	if err != nil {
 		if err == sql.ErrNoRows {
-			respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
-			return
+			return fail(http.StatusNotFound, "Couldn't find user subscription", err)
 		}
-		respondWithError(w, http.StatusInternalServerError, "Couldn't upgrade user", err)
+		return fail(http.StatusInternalServerError, "Couldn't update subscription", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fail(http.StatusInternalServerError, "Couldn't commit transaction", err)
+	}
+
+	res.outcome = webhookProcessed
+	res.code = http.StatusNoContent
+	return res
+}
+
+func respondWithWebhookResult(w http.ResponseWriter, res webhookResult) {
+	if res.msg != "" {
+		respondWithError(w, res.code, res.msg, res.err)
 		return
 	}
+	w.WriteHeader(res.code)
+}
+
+// rejectedWebhookLog trims an unverified delivery down to what's worth
+// keeping: a few headers and the start of the body
+func rejectedWebhookLog(headers http.Header, body []byte) (http.Header, []byte) {
+	kept := http.Header{}
+	for _, name := range rejectedWebhookHeaders {
+		if value := headers.Get(name); value != "" {
+			kept.Set(name, value[:min(len(value), rejectedWebhookLogBytes)])
+		}
+	}
+	return kept, body[:min(len(body), rejectedWebhookLogBytes)]
+}
+
+// logInboundWebhook stores a delivery as received. Logging is best effort: it
+// returns uuid.Nil rather than failing the delivery if the insert fails.
+func (cfg *apiConfig) logInboundWebhook(ctx context.Context, headers http.Header, body []byte, replayOf uuid.NullUUID) uuid.UUID {
+	// Credentials never belong in the log
+	headers = headers.Clone()
+	headers.Del("Authorization")
+	headers.Del("Cookie")
 
-	respondWithJSON(w, http.StatusNoContent, struct{}{})
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		log.Printf("Couldn't encode webhook headers: %s", err)
+		return uuid.Nil
+	}
+	entry, err := cfg.db.CreateInboundWebhook(ctx, database.CreateInboundWebhookParams{
+		Headers:  headersJSON,
+		Body:     body,
+		ReplayOf: replayOf,
+	})
+	if err != nil {
+		log.Printf("Couldn't log inbound webhook: %s", err)
+		return uuid.Nil
+	}
+	return entry.ID
+}
+
+func (cfg *apiConfig) finishInboundWebhook(ctx context.Context, id uuid.UUID, verified bool, verifyErr error, res webhookResult) {
+	if id == uuid.Nil {
+		return
+	}
+
+	params := database.FinishInboundWebhookParams{
+		ID:           id,
+		Verified:     verified,
+		EventID:      sql.NullString{String: res.eventID, Valid: res.eventID != ""},
+		Event:        sql.NullString{String: res.event, Valid: res.event != ""},
+		Outcome:      res.outcome,
+		ResponseCode: sql.NullInt32{Int32: int32(res.code), Valid: true},
+	}
+	if verifyErr != nil {
+		params.VerificationError = sql.NullString{String: verifyErr.Error(), Valid: true}
+	}
+	if res.err != nil {
+		params.Error = sql.NullString{String: res.err.Error(), Valid: true}
+	} else if res.msg != "" {
+		params.Error = sql.NullString{String: res.msg, Valid: true}
+	}
+
+	// The delivery may have been cancelled by the sender hanging up; the log
+	// should still say what happened
+	if err := cfg.db.FinishInboundWebhook(context.WithoutCancel(ctx), params); err != nil {
+		log.Printf("Couldn't update inbound webhook log: %s", err)
+	}
 }
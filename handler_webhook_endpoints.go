@@ -0,0 +1,293 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/auth"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type WebhookEndpoint struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	Secret              string     `json:"secret,omitempty"`
+}
+
+func webhookEndpointResponse(endpoint database.WebhookEndpoint) WebhookEndpoint {
+	resp := WebhookEndpoint{
+		ID:                  endpoint.ID,
+		CreatedAt:           endpoint.CreatedAt,
+		URL:                 endpoint.Url,
+		Events:              endpoint.EventTypes,
+		ConsecutiveFailures: endpoint.ConsecutiveFailures,
+	}
+	if endpoint.DisabledAt.Valid {
+		resp.DisabledAt = &endpoint.DisabledAt.Time
+	}
+	return resp
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID       `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	EventID       uuid.UUID       `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseCode  int32           `json:"response_code,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func webhookDeliveryResponse(delivery database.WebhookDelivery) WebhookDelivery {
+	resp := WebhookDelivery{
+		ID:           delivery.ID,
+		CreatedAt:    delivery.CreatedAt,
+		EventID:      delivery.EventID,
+		EventType:    delivery.EventType,
+		Status:       delivery.Status,
+		Attempts:     delivery.Attempts,
+		ResponseCode: delivery.ResponseCode.Int32,
+		LastError:    delivery.LastError.String,
+		Payload:      delivery.Payload,
+	}
+	if delivery.Status == "pending" {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		resp.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return resp
+}
+
+func (cfg *apiConfig) handlerWebhookEndpointsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	userID, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	// 1. Validate the endpoint. It must be a public https URL, so webhooks
+	// can't be used to reach the server's own network.
+	if err := cfg.webhookSender.CheckURL(r.Context(), params.URL); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Webhook URL must be a public https URL", err)
+		return
+	}
+	if len(params.Events) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one event is required", nil)
+		return
+	}
+	for _, event := range params.Events {
+		if !slices.Contains(validEventTypes, event) {
+			respondWithError(w, http.StatusBadRequest, "Unknown event: "+event, nil)
+			return
+		}
+	}
+	slices.Sort(params.Events)
+	params.Events = slices.Compact(params.Events)
+
+	// 2. The signing secret is shown once; the worker needs it in the clear
+	// to sign deliveries
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create signing secret", err)
+		return
+	}
+
+	endpoint, err := cfg.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		UserID:     userID,
+		Url:        params.URL,
+		Secret:     secret,
+		EventTypes: params.Events,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create webhook endpoint", err)
+		return
+	}
+
+	resp := webhookEndpointResponse(endpoint)
+	resp.Secret = secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) handlerWebhookEndpointsGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	endpoints, err := cfg.db.ListWebhookEndpoints(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhook endpoints", err)
+		return
+	}
+
+	results := []WebhookEndpoint{}
+	for _, endpoint := range endpoints {
+		results = append(results, webhookEndpointResponse(endpoint))
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func (cfg *apiConfig) handlerWebhookEndpointsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid endpoint ID", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete webhook endpoint", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Webhook endpoint not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerWebhookEndpointsEnable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return
+	}
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid endpoint ID", err)
+		return
+	}
+
+	endpoint, err := cfg.db.EnableWebhookEndpoint(r.Context(), database.EnableWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Webhook endpoint not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't enable webhook endpoint", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, webhookEndpointResponse(endpoint))
+}
+
+func (cfg *apiConfig) handlerWebhookDeliveriesGet(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := cfg.getOwnWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	deliveries, err := cfg.db.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve deliveries", err)
+		return
+	}
+
+	results := []WebhookDelivery{}
+	for _, delivery := range deliveries {
+		results = append(results, webhookDeliveryResponse(delivery))
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// handlerWebhookDeliveriesRetry puts a dead-lettered delivery back in the queue
+func (cfg *apiConfig) handlerWebhookDeliveriesRetry(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := cfg.getOwnWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID", err)
+		return
+	}
+
+	retried, err := cfg.db.RetryWebhookDelivery(r.Context(), database.RetryWebhookDeliveryParams{
+		ID:         deliveryID,
+		EndpointID: endpoint.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retry delivery", err)
+		return
+	}
+	if retried == 0 {
+		respondWithError(w, http.StatusNotFound, "No dead-lettered delivery found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// getOwnWebhookEndpoint authenticates the caller and loads the endpoint in the
+// path, responding with 404 if it belongs to someone else
+func (cfg *apiConfig) getOwnWebhookEndpoint(w http.ResponseWriter, r *http.Request) (database.WebhookEndpoint, bool) {
+	userID, ok := cfg.authenticate(w, r, scopeWebhooks)
+	if !ok {
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid endpoint ID", err)
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := cfg.db.GetWebhookEndpoint(r.Context(), endpointID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhook endpoint", err)
+		return database.WebhookEndpoint{}, false
+	}
+	if err == sql.ErrNoRows || endpoint.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "Webhook endpoint not found", err)
+		return database.WebhookEndpoint{}, false
+	}
+	return endpoint, true
+}
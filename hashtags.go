@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"workspace/github.com/kozykoding/chirpy/internal/chirptext"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// saveChirpHashtags indexes the hashtags in a chirp body, replacing any from
+// a previous version of the body
+func saveChirpHashtags(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := q.DeleteChirpHashtags(ctx, chirpID); err != nil {
+		return err
+	}
+	tags := chirptext.Hashtags(body)
+	if len(tags) == 0 {
+		return nil
+	}
+	return q.CreateChirpHashtags(ctx, database.CreateChirpHashtagsParams{
+		ChirpID: chirpID,
+		Tags:    tags,
+	})
+}
@@ -0,0 +1,270 @@
+// Package activitypub implements the parts of ActivityPub, WebFinger and
+// HTTP Signatures that Chirpy needs to federate with Mastodon and other
+// fediverse servers: the JSON vocabulary, signing and verifying requests,
+// and fetching from and delivering to remote servers.
+package activitypub
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"html"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+const (
+	// ContentType is what actors, objects and activities are served as
+	ContentType = "application/activity+json"
+	// LDContentType is the other media type servers ask for and send
+	LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+	// JRDContentType is what WebFinger responses are served as
+	JRDContentType = "application/jrd+json"
+
+	// Public addresses an activity to everyone
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// Context is the JSON-LD context of every document served
+var Context = []string{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+type Actor struct {
+	Context                   any        `json:"@context,omitempty"`
+	ID                        string     `json:"id"`
+	Type                      string     `json:"type"`
+	PreferredUsername         string     `json:"preferredUsername"`
+	Name                      string     `json:"name,omitempty"`
+	URL                       string     `json:"url,omitempty"`
+	Inbox                     string     `json:"inbox"`
+	Outbox                    string     `json:"outbox,omitempty"`
+	Followers                 string     `json:"followers,omitempty"`
+	Following                 string     `json:"following,omitempty"`
+	ManuallyApprovesFollowers bool       `json:"manuallyApprovesFollowers"`
+	PublicKey                 PublicKey  `json:"publicKey"`
+	Endpoints                 *Endpoints `json:"endpoints,omitempty"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+// SharedInbox is where deliveries to the actor go. Servers with a shared
+// inbox take one delivery for all of their followers.
+func (a Actor) SharedInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+type Note struct {
+	Context      any      `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo"`
+	Content      string   `json:"content"`
+	URL          string   `json:"url,omitempty"`
+	Published    string   `json:"published,omitempty"`
+	Updated      string   `json:"updated,omitempty"`
+	To           []string `json:"to,omitempty"`
+	Cc           []string `json:"cc,omitempty"`
+	InReplyTo    string   `json:"inReplyTo,omitempty"`
+}
+
+// Activity is any activity. Its object may be a link or an embedded object,
+// so it's left raw; see ObjectID.
+type Activity struct {
+	Context   any             `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	Published string          `json:"published,omitempty"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+}
+
+// NewActivity builds an activity around an object, which may be a link (a
+// string) or anything that marshals to a JSON object
+func NewActivity(id, activityType, actor string, object any) (Activity, error) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return Activity{}, err
+	}
+	return Activity{
+		Context: Context,
+		ID:      id,
+		Type:    activityType,
+		Actor:   actor,
+		Object:  raw,
+	}, nil
+}
+
+// ObjectID returns the ID of a link or embedded object
+func ObjectID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(raw, &object)
+	return object.ID
+}
+
+// ObjectType returns the type of an embedded object, or "" for a link
+func ObjectType(raw json.RawMessage) string {
+	var object struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(raw, &object)
+	return object.Type
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int64  `json:"totalItems"`
+	First        string `json:"first,omitempty"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// OrderedCollectionPage is one page of a collection too big to serve whole
+type OrderedCollectionPage struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	PartOf       string `json:"partOf"`
+	Next         string `json:"next,omitempty"`
+	Prev         string `json:"prev,omitempty"`
+	OrderedItems []any  `json:"orderedItems"`
+}
+
+// JRD is a WebFinger response
+type JRD struct {
+	Subject string    `json:"subject"`
+	Aliases []string  `json:"aliases,omitempty"`
+	Links   []JRDLink `json:"links"`
+}
+
+type JRDLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+// SelfLink returns the actor URL a WebFinger response points to
+func (j JRD) SelfLink() string {
+	for _, link := range j.Links {
+		if link.Rel == "self" && (link.Type == ContentType || strings.HasPrefix(link.Type, "application/ld+json")) {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// GenerateKey returns a new actor key pair, PEM-encoded
+func GenerateKey() (publicPEM, privatePEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}))
+	return publicPEM, privatePEM, nil
+}
+
+func ParsePrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("no PEM block in private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key isn't RSA")
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey accepts both PKIX and PKCS #1 encodings, since servers in
+// the wild use either
+func ParsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("no PEM block in public key")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key isn't RSA")
+	}
+	return rsaKey, nil
+}
+
+// NoteContent renders plain chirp text as the HTML a Note's content holds
+func NoteContent(text string) string {
+	paragraphs := []string{}
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.ReplaceAll(html.EscapeString(p), "\n", "<br>")
+		paragraphs = append(paragraphs, "<p>"+p+"</p>")
+	}
+	return strings.Join(paragraphs, "")
+}
+
+// PlainText turns a remote Note's HTML content back into text. Nothing from
+// the remote markup survives, so it's safe to show as is.
+func PlainText(content string) string {
+	var b strings.Builder
+	z := xhtml.NewTokenizer(strings.NewReader(content))
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case xhtml.TextToken:
+			b.Write(z.Text())
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken, xhtml.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "br":
+				b.WriteString("\n")
+			case "p":
+				if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n\n") {
+					b.WriteString("\n\n")
+				}
+			}
+		}
+	}
+}
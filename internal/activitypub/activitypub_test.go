@@ -0,0 +1,249 @@
+package activitypub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSigner(t *testing.T) (Signer, string) {
+	t.Helper()
+	publicPEM, privatePEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error: %v", err)
+	}
+	return Signer{KeyID: "https://a.example/ap/users/1#main-key", Key: key}, publicPEM
+}
+
+// signedRequest signs a client request and returns it as the server would
+// receive it
+func signedRequest(t *testing.T, signer Signer, body string, now time.Time) *http.Request {
+	t.Helper()
+	out, _ := http.NewRequest(http.MethodPost, "https://b.example/ap/users/2/inbox?x=1", strings.NewReader(body))
+	if err := Sign(out, []byte(body), signer.KeyID, signer.Key, now); err != nil {
+		t.Fatalf("Sign() error: %v", err)
+	}
+	in := httptest.NewRequest(http.MethodPost, "/ap/users/2/inbox?x=1", strings.NewReader(body))
+	in.Host = "b.example"
+	in.Header = out.Header.Clone()
+	return in
+}
+
+func TestSignatures(t *testing.T) {
+	signer, publicPEM := testSigner(t)
+	pub, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error: %v", err)
+	}
+	_, otherPEM := testSigner(t)
+	other, _ := ParsePublicKey(otherPEM)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	body := `{"type":"Follow"}`
+
+	tests := []struct {
+		name   string
+		mutate func(r *http.Request) []byte
+		now    time.Time
+		want   error
+	}{
+		{
+			name: "valid",
+			want: nil,
+		},
+		{
+			name: "body swapped",
+			mutate: func(r *http.Request) []byte {
+				return []byte(`{"type":"Undo"}`)
+			},
+			want: ErrDigestMismatch,
+		},
+		{
+			name: "digest and body swapped together",
+			mutate: func(r *http.Request) []byte {
+				r.Header.Set("Digest", Digest([]byte(`{"type":"Undo"}`)))
+				return []byte(`{"type":"Undo"}`)
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "sent to another inbox",
+			mutate: func(r *http.Request) []byte {
+				r.URL.Path = "/ap/users/3/inbox"
+				return nil
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "sent to another host",
+			mutate: func(r *http.Request) []byte {
+				r.Host = "c.example"
+				return nil
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "replayed much later",
+			now:  now.Add(MaxClockSkew + time.Minute),
+			want: ErrStaleSignature,
+		},
+		{
+			name: "digest not signed",
+			mutate: func(r *http.Request) []byte {
+				r.Header.Set("Signature", strings.Replace(r.Header.Get("Signature"), " digest", "", 1))
+				return nil
+			},
+			want: ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := signedRequest(t, signer, body, now)
+			received := []byte(body)
+			if tt.mutate != nil {
+				if b := tt.mutate(r); b != nil {
+					received = b
+				}
+			}
+			verifyAt := now
+			if !tt.now.IsZero() {
+				verifyAt = tt.now
+			}
+
+			sig, err := ParseSignature(r)
+			if err != nil {
+				t.Fatalf("ParseSignature() error: %v", err)
+			}
+			if sig.KeyID != signer.KeyID {
+				t.Errorf("KeyID = %q, want %q", sig.KeyID, signer.KeyID)
+			}
+			err = sig.Verify(r, received, pub, verifyAt)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Test: A signature doesn't verify against someone else's key
+	r := signedRequest(t, signer, body, now)
+	sig, _ := ParseSignature(r)
+	if err := sig.Verify(r, []byte(body), other, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() with the wrong key error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestClient(t *testing.T) {
+	signer, publicPEM := testSigner(t)
+	pub, _ := ParsePublicKey(publicPEM)
+
+	var server *httptest.Server
+	var delivered []byte
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actorURL := server.URL + "/ap/users/2"
+		switch r.URL.Path {
+		case "/.well-known/webfinger":
+			if r.URL.Query().Get("resource") != "acct:bob@"+r.Host {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(JRD{
+				Subject: "acct:bob@" + r.Host,
+				Links:   []JRDLink{{Rel: "self", Type: ContentType, Href: actorURL}},
+			})
+		case "/ap/users/2":
+			json.NewEncoder(w).Encode(Actor{
+				ID:        actorURL,
+				Type:      "Person",
+				Inbox:     actorURL + "/inbox",
+				PublicKey: PublicKey{ID: actorURL + "#main-key", Owner: actorURL, PublicKeyPem: publicPEM},
+			})
+		case "/ap/users/2/inbox":
+			body, _ := io.ReadAll(r.Body)
+			sig, err := ParseSignature(r)
+			if err == nil {
+				err = sig.Verify(r, body, pub, time.Now())
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			delivered = body
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// Test: The default client refuses plain HTTP and local addresses
+	strict := NewClient(time.Second, false)
+	if _, err := strict.FetchActor(ctx, server.URL+"/ap/users/2", nil); err == nil {
+		t.Error("strict client fetched from a local HTTP server")
+	}
+
+	client := NewClient(time.Second, true)
+	host := strings.TrimPrefix(server.URL, "http://")
+	actorURL, err := client.WebFinger(ctx, "@bob@"+host)
+	if err != nil {
+		t.Fatalf("WebFinger() error: %v", err)
+	}
+	if actorURL != server.URL+"/ap/users/2" {
+		t.Errorf("WebFinger() = %q", actorURL)
+	}
+
+	actor, err := client.FetchActor(ctx, actorURL, &signer)
+	if err != nil {
+		t.Fatalf("FetchActor() error: %v", err)
+	}
+	if actor.SharedInbox() != actorURL+"/inbox" {
+		t.Errorf("SharedInbox() = %q", actor.SharedInbox())
+	}
+
+	// Test: Deliveries are signed so the receiver can verify them
+	body := []byte(`{"type":"Create"}`)
+	if err := client.Deliver(ctx, actor.Inbox, body, signer); err != nil {
+		t.Fatalf("Deliver() error: %v", err)
+	}
+	if string(delivered) != string(body) {
+		t.Errorf("delivered %q, want %q", delivered, body)
+	}
+
+	// Test: A document claiming to be another actor is refused
+	if _, err := client.FetchActor(ctx, server.URL+"/ap/users/2?alias", nil); err == nil {
+		t.Error("FetchActor() accepted a document with a different ID")
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`<p>hello <a href="https://x.example"><span>@bob</span></a></p>`, "hello @bob"},
+		{`<p>one</p><p>two<br>three</p>`, "one\n\ntwo\nthree"},
+		{`<p>&lt;script&gt; &amp; friends</p><script>alert(1)</script>`, "<script> & friends\n\nalert(1)"},
+	}
+	for _, tt := range tests {
+		if got := PlainText(tt.content); got != tt.want {
+			t.Errorf("PlainText(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+
+	// Test: Chirp text survives the round trip through a Note
+	text := "a <b> & c\nline two\n\nsecond paragraph"
+	if got := PlainText(NoteContent(text)); got != text {
+		t.Errorf("PlainText(NoteContent(%q)) = %q", text, got)
+	}
+}
@@ -0,0 +1,195 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/netguard"
+)
+
+// maxDocumentBytes is the most read of any remote document
+const maxDocumentBytes = 1 << 20
+
+// StatusError is returned when a remote server answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("remote server responded with status %d", e.StatusCode)
+}
+
+// Signer identifies the local actor requests are signed as
+type Signer struct {
+	KeyID string
+	Key   *rsa.PrivateKey
+}
+
+// Client talks to remote servers. Remote URLs come from other servers, so
+// by default it only uses HTTPS and only connects to public addresses, the
+// same netguard check link previews use. AllowInsecure lifts both, for federating
+// instances on a local network.
+type Client struct {
+	HTTP          *http.Client
+	AllowInsecure bool
+	now           func() time.Time
+}
+
+func NewClient(timeout time.Duration, allowInsecure bool) *Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowInsecure {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !netguard.PublicAddress(addrPort.Addr().Unmap()) {
+				return fmt.Errorf("%w: %s", netguard.ErrBlockedAddress, addrPort)
+			}
+			return nil
+		},
+	}
+	return &Client{
+		HTTP: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          50,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			// A signature covers the URL it was made for, so redirects
+			// can't be followed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		AllowInsecure: allowInsecure,
+		now:           time.Now,
+	}
+}
+
+func (c *Client) checkURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || (u.Scheme != "https" && !(c.AllowInsecure && u.Scheme == "http")) {
+		return nil, fmt.Errorf("unsupported URL %q", rawURL)
+	}
+	return u, nil
+}
+
+// get fetches a JSON document, signed if signer isn't nil. Servers running
+// in "authorized fetch" mode refuse unsigned requests.
+func (c *Client) get(ctx context.Context, rawURL, accept string, signer *Signer, v any) error {
+	u, err := c.checkURL(rawURL)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "Chirpy-ActivityPub/1.0")
+	if signer != nil {
+		if err := Sign(req, nil, signer.KeyID, signer.Key, c.now()); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxDocumentBytes)).Decode(v)
+}
+
+// FetchActor fetches an actor document and checks its key belongs to it
+func (c *Client) FetchActor(ctx context.Context, actorURL string, signer *Signer) (Actor, error) {
+	var actor Actor
+	if err := c.get(ctx, actorURL, ContentType+", "+LDContentType, signer, &actor); err != nil {
+		return Actor{}, err
+	}
+	if actor.ID != actorURL {
+		return Actor{}, fmt.Errorf("actor document at %s claims to be %s", actorURL, actor.ID)
+	}
+	if actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" || actor.PublicKey.Owner != actor.ID {
+		return Actor{}, fmt.Errorf("actor %s has no inbox or key", actorURL)
+	}
+	return actor, nil
+}
+
+// WebFinger resolves an account such as "alice@example.com" to an actor URL
+func (c *Client) WebFinger(ctx context.Context, account string) (string, error) {
+	account = strings.TrimPrefix(strings.TrimPrefix(account, "acct:"), "@")
+	_, host, ok := strings.Cut(account, "@")
+	if !ok || host == "" || strings.ContainsAny(host, "/?#@") {
+		return "", fmt.Errorf("%q isn't an account address", account)
+	}
+
+	scheme := "https"
+	if c.AllowInsecure {
+		scheme = "http"
+	}
+	query := url.Values{"resource": {"acct:" + account}}
+	var jrd JRD
+	err := c.get(ctx, scheme+"://"+host+"/.well-known/webfinger?"+query.Encode(), JRDContentType+", application/json", nil, &jrd)
+	if err != nil {
+		return "", err
+	}
+	actorURL := jrd.SelfLink()
+	if actorURL == "" {
+		return "", fmt.Errorf("no actor for %s", account)
+	}
+	return actorURL, nil
+}
+
+// Deliver posts an activity to an inbox, signed as signer
+func (c *Client) Deliver(ctx context.Context, inbox string, body []byte, signer Signer) error {
+	u, err := c.checkURL(inbox)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", "Chirpy-ActivityPub/1.0")
+	if err := Sign(req, body, signer.KeyID, signer.Key, c.now()); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrStaleSignature   = errors.New("signature date outside tolerance window")
+	ErrDigestMismatch   = errors.New("digest doesn't match body")
+)
+
+// MaxClockSkew is how far a signed Date may be from now. Mastodon allows
+// the same.
+const MaxClockSkew = 12 * time.Hour
+
+// Sign signs the request as keyID, using the draft-cavage HTTP Signatures
+// scheme Mastodon and most of the fediverse use. POSTs also get a Digest of
+// the body, which the signature covers.
+func Sign(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey, now time.Time) error {
+	headers := []string{"(request-target)", "host", "date"}
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	if req.Method == http.MethodPost {
+		req.Header.Set("Digest", Digest(body))
+		headers = append(headers, "digest")
+	}
+
+	hash := sha256.Sum256([]byte(signingString(req, requestHost(req), headers)))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig),
+	))
+	return nil
+}
+
+// Digest is the Digest header value for a body
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Signature is a parsed Signature header
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Value     []byte
+}
+
+// ParseSignature reads the Signature header of an incoming request, so the
+// caller can look up the key it names
+func ParseSignature(r *http.Request) (Signature, error) {
+	header := r.Header.Get("Signature")
+	if header == "" {
+		return Signature{}, ErrMissingSignature
+	}
+
+	params := map[string]string{}
+	for _, part := range splitParams(header) {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return Signature{}, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+		}
+		params[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+
+	sig := Signature{
+		KeyID:     params["keyId"],
+		Algorithm: params["algorithm"],
+		Headers:   strings.Fields(params["headers"]),
+	}
+	if len(sig.Headers) == 0 {
+		sig.Headers = []string{"date"}
+	}
+	value, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || sig.KeyID == "" || len(value) == 0 {
+		return Signature{}, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	sig.Value = value
+	return sig, nil
+}
+
+// splitParams splits on commas outside quotes
+func splitParams(header string) []string {
+	parts := []string{}
+	quoted := false
+	start := 0
+	for i, c := range header {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, header[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, header[start:])
+}
+
+// Verify checks the signature against the key it names. The signature must
+// cover the request target, host and date, and for requests with a body
+// the digest too, so none of them can be swapped out.
+func (s Signature) Verify(r *http.Request, body []byte, key *rsa.PublicKey, now time.Time) error {
+	switch s.Algorithm {
+	case "", "rsa-sha256", "hs2019":
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, s.Algorithm)
+	}
+
+	required := []string{"(request-target)", "host", "date"}
+	if r.Method == http.MethodPost {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !slices.Contains(s.Headers, h) {
+			return fmt.Errorf("%w: %s isn't signed", ErrInvalidSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("%w: bad date", ErrInvalidSignature)
+	}
+	if date.Before(now.Add(-MaxClockSkew)) || date.After(now.Add(MaxClockSkew)) {
+		return ErrStaleSignature
+	}
+	if r.Method == http.MethodPost && r.Header.Get("Digest") != Digest(body) {
+		return ErrDigestMismatch
+	}
+
+	hash := sha256.Sum256([]byte(signingString(r, r.Host, s.Headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], s.Value); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func signingString(r *http.Request, host string, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(r.Method) + " " + r.URL.RequestURI()
+		case "host":
+			value = host
+		default:
+			value = strings.Join(r.Header.Values(h), ", ")
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// requestHost is the Host header an outgoing request will be sent with
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
@@ -24,31 +24,121 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return argon2id.ComparePasswordAndHash(password, hash)
 }
 
+const (
+	accessTokenIssuer = "chirpy"
+	mfaTokenIssuer    = "chirpy-mfa"
+)
+
+// claims are the registered JWT claims plus what third-party (OAuth) access
+// tokens are limited to
+type claims struct {
+	jwt.RegisteredClaims
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// AccessToken is what a validated access token grants
+type AccessToken struct {
+	UserID    uuid.UUID
+	ClientID  uuid.UUID // uuid.Nil for first-party tokens
+	Scopes    []string  // nil for first-party tokens, which can do anything
+	ExpiresAt time.Time
+}
+
 // MakeJWT creates a new JWT for a specific user ID
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return makeJWT(userID, tokenSecret, expiresIn, accessTokenIssuer, uuid.Nil, nil)
+}
+
+// MakeScopedJWT creates a JWT for a user acting through a third-party client.
+// It only grants the given scopes and is not accepted by ValidateJWT.
+func MakeScopedJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, clientID uuid.UUID, scopes []string) (string, error) {
+	return makeJWT(userID, tokenSecret, expiresIn, accessTokenIssuer, clientID, scopes)
+}
+
+// MakeMFAToken creates a short-lived token proving the password step of a
+// two-step login. It is not accepted by ValidateJWT.
+func MakeMFAToken(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return makeJWT(userID, tokenSecret, expiresIn, mfaTokenIssuer, uuid.Nil, nil)
+}
+
+func makeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, issuer string, clientID uuid.UUID, scopes []string) (string, error) {
 	signingKey := []byte(tokenSecret)
 
 	// Create the claims
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
+	tokenClaims := claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	}
+	if clientID != uuid.Nil {
+		tokenClaims.ClientID = clientID.String()
+		tokenClaims.Scope = strings.Join(scopes, " ")
 	}
 
 	// Create the token using HS256 and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims)
 
 	// Sign the token with the secret key
 	return token.SignedString(signingKey)
 }
 
-// ValidateJWT parses the token and returns the user ID if valid
+// ValidateJWT parses a first-party access token and returns the user ID if valid
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
+	access, err := ParseAccessToken(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if access.ClientID != uuid.Nil {
+		return uuid.Nil, errors.New("token was issued to a third-party client")
+	}
+	return access.UserID, nil
+}
+
+// ParseAccessToken parses any access token, first-party or scoped
+func ParseAccessToken(tokenString, tokenSecret string) (AccessToken, error) {
+	tokenClaims, err := validateJWT(tokenString, tokenSecret, accessTokenIssuer)
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	// Parse the Subject string back into a UUID
+	userID, err := uuid.Parse(tokenClaims.Subject)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("invalid user ID in token: %w", err)
+	}
+
+	access := AccessToken{
+		UserID:    userID,
+		ExpiresAt: tokenClaims.ExpiresAt.Time,
+	}
+	if tokenClaims.ClientID != "" {
+		access.ClientID, err = uuid.Parse(tokenClaims.ClientID)
+		if err != nil {
+			return AccessToken{}, fmt.Errorf("invalid client ID in token: %w", err)
+		}
+		access.Scopes = strings.Fields(tokenClaims.Scope)
+	}
+	return access, nil
+}
+
+// ValidateMFAToken parses a token made by MakeMFAToken and returns the user ID if valid
+func ValidateMFAToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	tokenClaims, err := validateJWT(tokenString, tokenSecret, mfaTokenIssuer)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(tokenClaims.Subject)
+}
+
+func validateJWT(tokenString, tokenSecret, issuer string) (*claims, error) {
+	claimsStruct := claims{}
 
 	// Parse the token with claims
-	token, err := jwt.ParseWithClaims(
+	_, err := jwt.ParseWithClaims(
 		tokenString,
 		&claimsStruct,
 		func(token *jwt.Token) (interface{}, error) {
@@ -58,24 +148,14 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 			}
 			return []byte(tokenSecret), nil
 		},
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
 	)
 	if err != nil {
-		return uuid.Nil, err
-	}
-
-	// Extract the Subject (UserID)
-	userIDString, err := token.Claims.GetSubject()
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	// Parse the Subject string back into a UUID
-	userID, err := uuid.Parse(userIDString)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID in token: %w", err)
+		return nil, err
 	}
 
-	return userID, nil
+	return &claimsStruct, nil
 }
 
 // GetBearerToken extracts the JWT from the Authorization header
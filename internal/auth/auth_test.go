@@ -39,3 +39,194 @@ func TestJWT(t *testing.T) {
 		t.Error("Validated token with wrong secret")
 	}
 }
+
+func TestLockoutPolicy(t *testing.T) {
+	policy := LockoutPolicy{
+		BackoffAfter: 3,
+		LockoutAfter: 6,
+		BaseDelay:    time.Second,
+		Lockout:      15 * time.Minute,
+	}
+
+	cases := map[int]time.Duration{
+		1: 0,
+		2: 0,
+		3: time.Second,
+		4: 2 * time.Second,
+		5: 4 * time.Second,
+		6: 15 * time.Minute,
+		9: 15 * time.Minute,
+	}
+	for failures, want := range cases {
+		if got := policy.Delay(failures); got != want {
+			t.Errorf("Delay(%d): expected %v, got %v", failures, want, got)
+		}
+	}
+
+	if !policy.IsLockout(6) || policy.IsLockout(7) {
+		t.Error("IsLockout should only trigger at the threshold")
+	}
+}
+
+func TestSignedToken(t *testing.T) {
+	secret := "my-super-secret-key"
+
+	token, err := MakeSignedToken(secret, "verify-email")
+	if err != nil {
+		t.Fatalf("Failed to make signed token: %v", err)
+	}
+
+	// Test: Valid Token
+	if err := VerifySignedToken(token, secret, "verify-email"); err != nil {
+		t.Errorf("Failed to verify valid token: %v", err)
+	}
+
+	// Test: Wrong Purpose
+	if err := VerifySignedToken(token, secret, "password-reset"); err == nil {
+		t.Error("Verified token for the wrong purpose")
+	}
+
+	// Test: Wrong Secret
+	if err := VerifySignedToken(token, "wrong-secret", "verify-email"); err == nil {
+		t.Error("Verified token with wrong secret")
+	}
+
+	// Test: Tampered Token
+	if err := VerifySignedToken("x"+token, secret, "verify-email"); err == nil {
+		t.Error("Verified tampered token")
+	}
+}
+
+func TestSignedLink(t *testing.T) {
+	secret := "my-super-secret-key"
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(15 * time.Minute)
+
+	signature := SignLink(secret, "data-export", "export-1", expires)
+
+	// Test: Valid Link
+	if err := VerifyLink(signature, secret, "data-export", "export-1", expires, now); err != nil {
+		t.Errorf("Failed to verify valid link: %v", err)
+	}
+
+	// Test: Expired Link
+	if err := VerifyLink(signature, secret, "data-export", "export-1", expires, expires); err == nil {
+		t.Error("Verified an expired link")
+	}
+
+	// Test: Expiry Pushed Back
+	if err := VerifyLink(signature, secret, "data-export", "export-1", expires.Add(time.Hour), now); err == nil {
+		t.Error("Verified link with a changed expiry")
+	}
+
+	// Test: Another Payload
+	if err := VerifyLink(signature, secret, "data-export", "export-2", expires, now); err == nil {
+		t.Error("Verified link for another payload")
+	}
+
+	// Test: Wrong Purpose
+	if err := VerifyLink(signature, secret, "password-reset", "export-1", expires, now); err == nil {
+		t.Error("Verified link for the wrong purpose")
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 test vector secret ("12345678901234567890")
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(59, 0)
+
+	// Test: Known code for T=59
+	step, ok := ValidateTOTP("287082", secret, now)
+	if !ok {
+		t.Fatal("Failed to validate RFC 6238 test vector")
+	}
+	if step != 1 {
+		t.Errorf("Expected step 1, got %d", step)
+	}
+
+	// Test: Code from the previous step is still accepted
+	if _, ok := ValidateTOTP("287082", secret, now.Add(30*time.Second)); !ok {
+		t.Error("Rejected code within skew window")
+	}
+
+	// Test: Stale code is rejected
+	if _, ok := ValidateTOTP("287082", secret, now.Add(5*time.Minute)); ok {
+		t.Error("Validated stale code")
+	}
+
+	// Test: Wrong code is rejected
+	if _, ok := ValidateTOTP("000000", secret, now); ok {
+		t.Error("Validated wrong code")
+	}
+}
+
+func TestMFAToken(t *testing.T) {
+	secret := "my-super-secret-key"
+	userID := uuid.New()
+
+	mfaToken, err := MakeMFAToken(userID, secret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to make MFA token: %v", err)
+	}
+
+	// Test: Valid MFA Token
+	parsedID, err := ValidateMFAToken(mfaToken, secret)
+	if err != nil {
+		t.Fatalf("Failed to validate MFA token: %v", err)
+	}
+	if parsedID != userID {
+		t.Errorf("Expected ID %v, got %v", userID, parsedID)
+	}
+
+	// Test: MFA Token is not an access token
+	if _, err := ValidateJWT(mfaToken, secret); err == nil {
+		t.Error("Validated MFA token as an access token")
+	}
+
+	// Test: Access token is not an MFA token
+	accessToken, _ := MakeJWT(userID, secret, time.Hour)
+	if _, err := ValidateMFAToken(accessToken, secret); err == nil {
+		t.Error("Validated access token as an MFA token")
+	}
+}
+
+func TestScopedJWT(t *testing.T) {
+	secret := "my-super-secret-key"
+	userID := uuid.New()
+	clientID := uuid.New()
+
+	token, err := MakeScopedJWT(userID, secret, time.Hour, clientID, []string{"chirps:read"})
+	if err != nil {
+		t.Fatalf("Failed to make scoped JWT: %v", err)
+	}
+
+	// Test: Scopes and client survive the round trip
+	access, err := ParseAccessToken(token, secret)
+	if err != nil {
+		t.Fatalf("Failed to parse scoped JWT: %v", err)
+	}
+	if access.UserID != userID || access.ClientID != clientID {
+		t.Errorf("Expected user %v and client %v, got %v and %v", userID, clientID, access.UserID, access.ClientID)
+	}
+	if len(access.Scopes) != 1 || access.Scopes[0] != "chirps:read" {
+		t.Errorf("Expected [chirps:read], got %v", access.Scopes)
+	}
+
+	// Test: Scoped tokens aren't full sessions
+	if _, err := ValidateJWT(token, secret); err == nil {
+		t.Error("Validated scoped token as a first-party access token")
+	}
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	// RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if !VerifyPKCE(verifier, challenge) {
+		t.Error("Failed to verify RFC 7636 test vector")
+	}
+	if VerifyPKCE(verifier+"x", challenge) {
+		t.Error("Verified wrong code verifier")
+	}
+}
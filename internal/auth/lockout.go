@@ -0,0 +1,50 @@
+package auth
+
+import "time"
+
+// LockoutPolicy decides how long a login key is blocked after repeated failures
+type LockoutPolicy struct {
+	BackoffAfter int           // failures allowed before delays kick in
+	LockoutAfter int           // failures before the key is locked out
+	BaseDelay    time.Duration // first delay, doubled for every further failure
+	Lockout      time.Duration // how long a lockout lasts
+}
+
+// Delay returns how long further attempts should be refused after the given
+// number of consecutive failures
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if failures >= p.LockoutAfter {
+		return p.Lockout
+	}
+	if failures < p.BackoffAfter {
+		return 0
+	}
+	delay := p.BaseDelay << (failures - p.BackoffAfter)
+	if delay <= 0 || delay > p.Lockout {
+		return p.Lockout
+	}
+	return delay
+}
+
+// IsLockout reports whether failures is exactly the count that triggers a lockout
+func (p LockoutPolicy) IsLockout(failures int) bool {
+	return failures == p.LockoutAfter
+}
+
+// dummyHash is computed once at startup, so the first unknown email isn't
+// slowed down by generating it
+var dummyHash string
+
+func init() {
+	var err error
+	dummyHash, err = HashPassword("chirpy-dummy-password")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CheckDummyPasswordHash does the same work as CheckPasswordHash against a
+// throwaway hash, so that unknown emails take as long to reject as wrong passwords
+func CheckDummyPasswordHash(password string) {
+	CheckPasswordHash(password, dummyHash)
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MakeSignedToken generates a random token signed with an HMAC of the secret.
+// The purpose is mixed into the signature so a token issued for one flow can't
+// be replayed against another.
+func MakeSignedToken(secret, purpose string) (string, error) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(bytes)
+	return payload + "." + signToken(secret, purpose, payload), nil
+}
+
+// VerifySignedToken checks the signature of a token made by MakeSignedToken
+func VerifySignedToken(token, secret, purpose string) error {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return errors.New("malformed token")
+	}
+	expected := signToken(secret, purpose, payload)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return errors.New("invalid token signature")
+	}
+	return nil
+}
+
+// SignLink signs a payload for a link that grants access on its own until
+// expires, such as a download link. Like MakeSignedToken, the purpose is
+// mixed into the signature.
+func SignLink(secret, purpose, payload string, expires time.Time) string {
+	return signToken(secret, purpose, payload+"."+strconv.FormatInt(expires.Unix(), 10))
+}
+
+// VerifyLink checks a signature made by SignLink and that the link hasn't
+// expired
+func VerifyLink(signature, secret, purpose, payload string, expires, now time.Time) error {
+	expected := SignLink(secret, purpose, payload, expires)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return errors.New("invalid link signature")
+	}
+	if !now.Before(expires) {
+		return errors.New("link has expired")
+	}
+	return nil
+}
+
+// HashToken returns the hex-encoded SHA-256 of a token, for storing tokens at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func signToken(secret, purpose, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PersonalAccessTokenPrefix marks bearer tokens that are personal access
+// tokens rather than JWTs
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+// MakePersonalAccessToken generates a random personal access token
+func MakePersonalAccessToken() (string, error) {
+	random, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + random, nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
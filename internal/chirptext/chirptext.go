@@ -0,0 +1,168 @@
+// Package chirptext measures chirp text the way people read it: in
+// user-perceived characters (grapheme clusters) of the NFC-normalized text,
+// with every URL up to MaxURLLength counting the same however long it is.
+package chirptext
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLWeight is how many characters a URL counts as
+const URLWeight = 23
+
+// MaxURLLength is the longest URL, in bytes, that counts as URLWeight. Longer
+// ones count character by character, so a chirp can't smuggle in kilobytes
+// of text as a "URL".
+const MaxURLLength = 512
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// Punctuation that usually ends the sentence around a URL rather than the URL
+const urlTrailingPunctuation = `.,:;!?'")]}`
+
+// TooLongError reports how far over the limit a chirp is
+type TooLongError struct {
+	Length int
+	Limit  int
+}
+
+func (e *TooLongError) Error() string {
+	return fmt.Sprintf("Chirp is too long: %d characters over the limit of %d", e.Over(), e.Limit)
+}
+
+// Over is how many characters have to go
+func (e *TooLongError) Over() int {
+	return e.Length - e.Limit
+}
+
+// Normalize returns the text in NFC, so that e.g. "é" typed as "e" plus a
+// combining accent is stored and counted the same as the precomposed form
+func Normalize(text string) string {
+	return norm.NFC.String(text)
+}
+
+// URLs returns the byte ranges of the URLs in the text
+func URLs(text string) [][2]int {
+	ranges := [][2]int{}
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		for end > start && strings.ContainsRune(urlTrailingPunctuation, rune(text[end-1])) {
+			// A closing paren belongs to the URL if it has a matching one,
+			// as in Wikipedia links
+			if text[end-1] == ')' && strings.Count(text[start:end], "(") >= strings.Count(text[start:end], ")") {
+				break
+			}
+			end--
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
+
+// URLEntity is a URL found in chirp text. Start and End (exclusive) are
+// offsets in Unicode code points.
+type URLEntity struct {
+	Start       int
+	End         int
+	URL         string // as written
+	ExpandedURL string // normalized, and what link previews are fetched for
+	DisplayURL  string // without the scheme, shortened if long
+}
+
+const maxDisplayURLLength = 30
+
+// Entities returns the URLs in the text
+func Entities(text string) []URLEntity {
+	entities := []URLEntity{}
+	for _, r := range URLs(text) {
+		raw := text[r[0]:r[1]]
+		expanded := raw
+		if u, err := url.Parse(raw); err == nil {
+			u.Scheme = strings.ToLower(u.Scheme)
+			u.Host = strings.ToLower(u.Host)
+			expanded = u.String()
+		}
+
+		display := raw[strings.Index(raw, "://")+3:]
+		if utf8.RuneCountInString(display) > maxDisplayURLLength {
+			display = string([]rune(display)[:maxDisplayURLLength-1]) + "…"
+		}
+
+		start := utf8.RuneCountInString(text[:r[0]])
+		entities = append(entities, URLEntity{
+			Start:       start,
+			End:         start + utf8.RuneCountInString(raw),
+			URL:         raw,
+			ExpandedURL: expanded,
+			DisplayURL:  display,
+		})
+	}
+	return entities
+}
+
+// A hashtag is # followed by letters, digits and underscores, with at least
+// one letter, and not glued to the word before it
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*)`)
+
+// MaxHashtagLength is the longest hashtag recognized, in code points
+const MaxHashtagLength = 100
+
+// Hashtags returns the distinct hashtags in the text, lowercased and without
+// the #. Fragments of URLs aren't hashtags.
+func Hashtags(text string) []string {
+	urls := URLs(text)
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, loc := range hashtagPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[2], loc[3]
+		if inRanges(urls, start) || utf8.RuneCountInString(text[start:end]) > MaxHashtagLength {
+			continue
+		}
+		tag := strings.ToLower(text[start:end])
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func inRanges(ranges [][2]int, i int) bool {
+	for _, r := range ranges {
+		if i >= r[0] && i < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Length is the weighted length of already normalized text
+func Length(text string) int {
+	length := 0
+	prev := 0
+	for _, r := range URLs(text) {
+		if r[1]-r[0] > MaxURLLength {
+			continue
+		}
+		length += uniseg.GraphemeClusterCount(text[prev:r[0]]) + URLWeight
+		prev = r[1]
+	}
+	return length + uniseg.GraphemeClusterCount(text[prev:])
+}
+
+// Validate normalizes the text and checks it against the limit, returning a
+// *TooLongError if it's over
+func Validate(text string, limit int) (string, error) {
+	text = Normalize(text)
+	if length := Length(text); length > limit {
+		return "", &TooLongError{Length: length, Limit: limit}
+	}
+	return text, nil
+}
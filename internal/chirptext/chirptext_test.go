@@ -0,0 +1,109 @@
+package chirptext
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"ascii", "hello world", 11},
+		{"emoji", strings.Repeat("😀", 50), 50},
+		{"family emoji is one character", "👨‍👩‍👧‍👦", 1},
+		{"flag is one character", "🇳🇿", 1},
+		{"combining accent", Normalize("cafe\u0301"), 4},
+		{"url", "see https://example.com/a/very/long/path?with=query", 4 + URLWeight},
+		{"short url", "http://x.io", URLWeight},
+		{"trailing punctuation isn't part of the url", "(https://example.com).", URLWeight + 3},
+		{"balanced parens are", "https://en.wikipedia.org/wiki/Go_(language)", URLWeight},
+		{"overlong url counts in full", "https://x.io/" + strings.Repeat("a", MaxURLLength), len("https://x.io/") + MaxURLLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.text); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// Test: Normalization happens before counting and is returned
+	got, err := Validate("cafe\u0301", 4)
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if got != "caf\u00e9" {
+		t.Errorf("Validate() = %q, want NFC form", got)
+	}
+
+	// Test: Over the limit says by how much
+	_, err = Validate(strings.Repeat("😀", 145), 140)
+	var tooLong *TooLongError
+	if !errors.As(err, &tooLong) {
+		t.Fatalf("Validate() error = %v, want *TooLongError", err)
+	}
+	if tooLong.Length != 145 || tooLong.Limit != 140 || tooLong.Over() != 5 {
+		t.Errorf("TooLongError = %+v, over %d", tooLong, tooLong.Over())
+	}
+}
+
+func TestEntities(t *testing.T) {
+	text := "😀 read HTTPS://Example.com/a/really/long/path/to/an/article and http://x.io."
+	got := Entities(text)
+	want := []URLEntity{
+		{
+			Start:       7,
+			End:         59,
+			URL:         "HTTPS://Example.com/a/really/long/path/to/an/article",
+			ExpandedURL: "https://example.com/a/really/long/path/to/an/article",
+			DisplayURL:  "Example.com/a/really/long/pat…",
+		},
+		{
+			Start:       64,
+			End:         75,
+			URL:         "http://x.io",
+			ExpandedURL: "http://x.io",
+			DisplayURL:  "x.io",
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Entities() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Entities()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"simple", "loving #golang today", []string{"golang"}},
+		{"lowercased and deduped", "#Go #go #GO", []string{"go"}},
+		{"start and punctuation", "#one, (#two) and #three.", []string{"one", "two", "three"}},
+		{"unicode", "#café #日本語", []string{"café", "日本語"}},
+		{"digits only isn't a tag", "#1 fan, #2024goals", []string{"2024goals"}},
+		{"glued to a word isn't a tag", "issue#42 and a#b", []string{}},
+		{"url fragments aren't tags", "https://example.com/page#section #real", []string{"real"}},
+		{"double hash", "##nope", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.text)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Hashtags(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,17 +6,296 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ActivityDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	InboxUrl      string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	DeliveredAt   sql.NullTime
+}
+
+type ActorDeletion struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Body       string
+	UserID     uuid.UUID
+	Visibility string
+}
+
+type ChirpHashtag struct {
+	ChirpID uuid.UUID
+	Tag     string
+}
+
+type ChirpLink struct {
+	ChirpID     uuid.UUID
+	Position    int32
+	StartIndex  int32
+	EndIndex    int32
+	Url         string
+	ExpandedUrl string
+	DisplayUrl  string
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+	CreatedBy uuid.NullUUID
+	IsGroup   bool
+}
+
+type ConversationParticipant struct {
+	ConversationID    uuid.UUID
+	UserID            uuid.UUID
+	JoinedAt          time.Time
+	LastReadMessageID uuid.NullUUID
+	LastReadAt        sql.NullTime
+}
+
+type DataExport struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	Archive       []byte
+	SizeBytes     sql.NullInt64
+	LastError     sql.NullString
+	CompletedAt   sql.NullTime
+	ExpiresAt     sql.NullTime
+}
+
+type Draft struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Body       string
+	PublishAt  sql.NullTime
+	Visibility string
+	Mentions   []uuid.UUID
+}
+
+type EmailOutbox struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ToAddress     string
+	Subject       string
+	Body          string
+	Attempts      int32
+	NextAttemptAt time.Time
+	SentAt        sql.NullTime
+	LastError     sql.NullString
+	Status        string
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+	CreatedAt   time.Time
+}
+
+type InboundWebhook struct {
+	ID                uuid.UUID
+	ReceivedAt        time.Time
+	Headers           json.RawMessage
+	Body              []byte
+	ReplayOf          uuid.NullUUID
+	Verified          bool
+	VerificationError sql.NullString
+	EventID           sql.NullString
+	Event             sql.NullString
+	Outcome           string
+	ResponseCode      sql.NullInt32
+	Error             sql.NullString
+	ProcessedAt       sql.NullTime
+}
+
+type LinkPreview struct {
+	Url           string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	FetchedAt     sql.NullTime
+	FinalUrl      string
+	Title         string
+	Description   string
+	ImageUrl      string
+	SiteName      string
+}
+
+type List struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Description string
+	IsPrivate   bool
+}
+
+type ListFollow struct {
+	ListID    uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ListMember struct {
+	ListID    uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type LoginFailure struct {
+	Key         string
+	Failures    int32
+	LockedUntil sql.NullTime
+	UpdatedAt   time.Time
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	CreatedAt     time.Time
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+}
+
+type Poll struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ChirpID     uuid.UUID
+	ClosesAt    time.Time
+	HideResults bool
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	PollID   uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
+	UserID    uuid.UUID
+	OptionID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
 }
 
 type RefreshToken struct {
@@ -26,13 +305,130 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	ClientID  uuid.NullUUID
+	Scopes    []string
 }
 
-type User struct {
+type RemoteActor struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ActorUrl       string
+	InboxUrl       string
+	SharedInboxUrl string
+	KeyID          string
+	PublicKeyPem   string
+	Username       string
+}
+
+type RemoteFollower struct {
+	RemoteActorID uuid.UUID
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	ActivityID    string
+}
+
+type RemoteFollowing struct {
+	UserID        uuid.UUID
+	RemoteActorID uuid.UUID
+	CreatedAt     time.Time
+	ActivityID    string
+	AcceptedAt    sql.NullTime
+}
+
+type RemoteLike struct {
+	RemoteActorID uuid.UUID
+	ChirpID       uuid.UUID
+	CreatedAt     time.Time
+	ActivityID    string
+}
+
+type RemoteNote struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	RemoteActorID uuid.UUID
+	ObjectID      string
+	Url           string
+	Content       string
+	PublishedAt   time.Time
+}
+
+type Subscription struct {
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	UserID             uuid.UUID
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+}
+
+type SubscriptionEvent struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	SubscriptionID uuid.UUID
+	Event          string
+	Status         string
+	PeriodEnd      time.Time
+}
+
+type User struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Email               string
+	HashedPassword      string
+	IsChirpyRed         bool
+	EmailVerifiedAt     sql.NullTime
+	PinnedChirpID       uuid.NullUUID
+	IsPrivate           bool
+	DeletionScheduledAt sql.NullTime
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	CreatedAt    time.Time
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	EndpointID    uuid.UUID
+	EventID       uuid.UUID
+	EventType     string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	ResponseCode  sql.NullInt32
+	LastError     sql.NullString
+	DeliveredAt   sql.NullTime
+}
+
+type WebhookEndpoint struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	UserID              uuid.UUID
+	Url                 string
+	Secret              string
+	EventTypes          []string
+	ConsecutiveFailures int32
+	DisabledAt          sql.NullTime
+}
+
+type WebhookEvent struct {
+	EventID    string
+	Event      string
+	ReceivedAt time.Time
 }
@@ -0,0 +1,133 @@
+// Package entitlements maps subscription plans to what they unlock. Plan
+// contents come from config so product can change them without a deploy.
+package entitlements
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type Plan string
+
+const (
+	PlanFree Plan = "free"
+	PlanRed  Plan = "red"
+)
+
+// Known reports whether the plan is one the service sells
+func (p Plan) Known() bool {
+	return p == PlanFree || p == PlanRed
+}
+
+// PlanFor returns the plan a user is on
+func PlanFor(isChirpyRed bool) Plan {
+	if isChirpyRed {
+		return PlanRed
+	}
+	return PlanFree
+}
+
+// Feature is an on/off capability handlers can require
+type Feature string
+
+const (
+	FeatureChirpEditing    Feature = "chirp_editing"
+	FeatureScheduledChirps Feature = "scheduled_chirps"
+)
+
+// Capabilities is everything a plan unlocks
+type Capabilities struct {
+	MaxChirpLength      int     `json:"max_chirp_length"`
+	RateLimitMultiplier float64 `json:"rate_limit_multiplier"`
+	// MaxMediaPerChirp is reserved for media uploads; chirps carry no media
+	// yet, so nothing enforces it
+	MaxMediaPerChirp int  `json:"max_media_per_chirp"`
+	ChirpEditing     bool `json:"chirp_editing"`
+	ScheduledChirps  bool `json:"scheduled_chirps"`
+}
+
+// Has reports whether the feature is enabled
+func (c Capabilities) Has(feature Feature) bool {
+	switch feature {
+	case FeatureChirpEditing:
+		return c.ChirpEditing
+	case FeatureScheduledChirps:
+		return c.ScheduledChirps
+	}
+	return false
+}
+
+// Config holds the capabilities of every plan
+type Config map[Plan]Capabilities
+
+// Default is used for any plan or field the config file leaves out
+func Default() Config {
+	return Config{
+		PlanFree: {
+			MaxChirpLength:      140,
+			RateLimitMultiplier: 1,
+			MaxMediaPerChirp:    1,
+		},
+		PlanRed: {
+			MaxChirpLength:      500,
+			RateLimitMultiplier: 3,
+			MaxMediaPerChirp:    4,
+			ChirpEditing:        true,
+			ScheduledChirps:     true,
+		},
+	}
+}
+
+// For returns the capabilities of a plan, falling back to the free plan for
+// unknown ones
+func (c Config) For(plan Plan) Capabilities {
+	if caps, ok := c[plan]; ok {
+		return caps
+	}
+	return c[PlanFree]
+}
+
+// Load reads a JSON file of the form {"red": {"max_chirp_length": 280}}.
+// Fields it sets override the defaults; everything else keeps its default.
+// Unknown plans and fields are errors, so a typo can't silently do nothing.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse is Load for an in-memory config
+func Parse(data []byte) (Config, error) {
+	raw := map[Plan]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid entitlements config: %w", err)
+	}
+
+	cfg := Default()
+	for plan, overrides := range raw {
+		if !plan.Known() {
+			return nil, fmt.Errorf("unknown plan %q", plan)
+		}
+		caps := cfg.For(plan)
+		dec := json.NewDecoder(bytes.NewReader(overrides))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&caps); err != nil {
+			return nil, fmt.Errorf("invalid entitlements for plan %q: %w", plan, err)
+		}
+		if caps.MaxChirpLength < 1 {
+			return nil, fmt.Errorf("plan %q: max_chirp_length must be positive", plan)
+		}
+		if caps.RateLimitMultiplier <= 0 {
+			return nil, fmt.Errorf("plan %q: rate_limit_multiplier must be positive", plan)
+		}
+		if caps.MaxMediaPerChirp < 0 {
+			return nil, fmt.Errorf("plan %q: max_media_per_chirp can't be negative", plan)
+		}
+		cfg[plan] = caps
+	}
+	return cfg, nil
+}
@@ -0,0 +1,47 @@
+package entitlements
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`{"red": {"max_chirp_length": 280, "chirp_editing": false}}`))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	// Test: Overridden fields change, the rest keep their defaults
+	red := cfg.For(PlanRed)
+	if red.MaxChirpLength != 280 || red.Has(FeatureChirpEditing) {
+		t.Errorf("Red overrides not applied: %+v", red)
+	}
+	if !red.Has(FeatureScheduledChirps) || red.RateLimitMultiplier != Default()[PlanRed].RateLimitMultiplier {
+		t.Errorf("Red defaults lost: %+v", red)
+	}
+	if cfg.For(PlanFree) != Default()[PlanFree] {
+		t.Errorf("Free plan changed: %+v", cfg.For(PlanFree))
+	}
+
+	// Test: Unknown plans fall back to free
+	if cfg.For("gold") != cfg.For(PlanFree) {
+		t.Errorf("Unknown plan didn't fall back to free")
+	}
+
+	// Test: Nonsense limits are rejected
+	if _, err := Parse([]byte(`{"free": {"max_chirp_length": 0}}`)); err == nil {
+		t.Errorf("Expected error for zero chirp length")
+	}
+	if _, err := Parse([]byte(`{"free": {"rate_limit_multiplier": -1}}`)); err == nil {
+		t.Errorf("Expected error for negative multiplier")
+	}
+
+	if _, err := Parse([]byte(`{"free": {"max_media_per_chirp": -1}}`)); err == nil {
+		t.Errorf("Expected error for negative media limit")
+	}
+
+	// Test: Typos in plans and fields are rejected rather than ignored
+	if _, err := Parse([]byte(`{"gold": {"max_chirp_length": 280}}`)); err == nil {
+		t.Errorf("Expected error for unknown plan")
+	}
+	if _, err := Parse([]byte(`{"red": {"max_chrip_length": 280}}`)); err == nil {
+		t.Errorf("Expected error for unknown field")
+	}
+}
@@ -0,0 +1,187 @@
+package feed
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return Feed{
+		ID:      "https://chirpy.example/users/1/feed.atom",
+		Title:   "Chirps by 1",
+		SelfURL: "https://chirpy.example/users/1/feed.atom",
+		Link:    "https://chirpy.example/api/chirps?author_id=1",
+		Updated: created.Add(-time.Hour),
+		Entries: []Entry{
+			{
+				ID:        "urn:uuid:2",
+				Title:     "edited <b>later</b>",
+				Link:      "https://chirpy.example/api/chirps/2",
+				Author:    "1",
+				Content:   "edited <b>later</b> & more",
+				Published: created.Add(time.Minute),
+				Updated:   created.Add(time.Hour),
+			},
+			{
+				ID:        "urn:uuid:1",
+				Title:     "first",
+				Link:      "https://chirpy.example/api/chirps/1",
+				Author:    "1",
+				Content:   "first",
+				Published: created,
+				Updated:   created,
+			},
+		},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := testFeed().Atom()
+	if err != nil {
+		t.Fatalf("Atom() error: %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("Atom output doesn't parse: %v\n%s", err, body)
+	}
+
+	// Test: The feed is as new as its newest edit
+	if doc.Updated != "2024-05-01T13:00:00Z" {
+		t.Errorf("feed updated = %q, want 2024-05-01T13:00:00Z", doc.Updated)
+	}
+	if len(doc.Entries) != 2 || doc.Entries[0].ID != "urn:uuid:2" {
+		t.Fatalf("unexpected entries: %+v", doc.Entries)
+	}
+	// Test: Markup in chirps is escaped text, not HTML
+	if doc.Entries[0].Content != "edited <b>later</b> & more" {
+		t.Errorf("content = %q", doc.Entries[0].Content)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := testFeed().RSS()
+	if err != nil {
+		t.Fatalf("RSS() error: %v", err)
+	}
+	if !strings.Contains(string(body), `<atom:link rel="self" type="application/rss+xml" href="https://chirpy.example/users/1/feed.atom">`) {
+		t.Errorf("RSS output is missing its self link:\n%s", body)
+	}
+
+	var doc struct {
+		Channel struct {
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("RSS output doesn't parse: %v\n%s", err, body)
+	}
+	if doc.Channel.LastBuildDate != "Wed, 01 May 2024 13:00:00 +0000" {
+		t.Errorf("lastBuildDate = %q", doc.Channel.LastBuildDate)
+	}
+	if len(doc.Channel.Items) != 2 || doc.Channel.Items[1].GUID != "urn:uuid:1" {
+		t.Fatalf("unexpected items: %+v", doc.Channel.Items)
+	}
+}
+
+func TestServe(t *testing.T) {
+	f := testFeed()
+	body, err := f.Atom()
+	if err != nil {
+		t.Fatalf("Atom() error: %v", err)
+	}
+	etag := f.ETag()
+	lastModified := f.LastModified()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"unconditional", nil, http.StatusOK},
+		{"matching etag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"etag in a list", map[string]string{"If-None-Match": `"stale", W/` + etag}, http.StatusNotModified},
+		{"stale etag", map[string]string{"If-None-Match": `"stale"`}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": lastModified.Add(-time.Second).Format(http.TimeFormat)}, http.StatusOK},
+		{"etag wins over date", map[string]string{
+			"If-None-Match":     `"stale"`,
+			"If-Modified-Since": lastModified.Format(http.TimeFormat),
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feed.atom", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			Serve(rec, req, f, AtomContentType, body)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Header().Get("ETag") != etag {
+				t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), etag)
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 response has a body")
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != string(body) {
+				t.Errorf("body doesn't match the rendered feed")
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	f := testFeed()
+	etag := f.ETag()
+
+	// Test: An edited entry changes the ETag
+	edited := testFeed()
+	edited.Entries[1].Updated = edited.Entries[1].Updated.Add(time.Second)
+	if edited.ETag() == etag {
+		t.Errorf("ETag didn't change after an edit")
+	}
+
+	// Test: So does a new entry
+	added := testFeed()
+	added.Entries = append([]Entry{{ID: "urn:uuid:3"}}, added.Entries...)
+	if added.ETag() == etag {
+		t.Errorf("ETag didn't change after a new entry")
+	}
+
+	// Test: An empty feed has no Last-Modified, but still a date in its body
+	empty := Feed{ID: "https://chirpy.example/tags/go/feed.atom"}
+	req := httptest.NewRequest(http.MethodGet, "/feed.atom", nil)
+	rec := httptest.NewRecorder()
+	body, err := empty.Atom()
+	if err != nil {
+		t.Fatalf("Atom() error: %v", err)
+	}
+	Serve(rec, req, empty, AtomContentType, body)
+	if rec.Header().Get("Last-Modified") != "" {
+		t.Errorf("Last-Modified = %q, want none", rec.Header().Get("Last-Modified"))
+	}
+	if strings.Contains(string(body), "1970-01-01") || strings.Contains(string(body), "0001-01-01") {
+		t.Errorf("empty feed has a placeholder date:\n%s", body)
+	}
+}
@@ -0,0 +1,138 @@
+// Package linkpreview builds preview cards for links in chirps from the
+// page's OpenGraph and meta tags.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/netguard"
+)
+
+// Card is what a link preview shows
+type Card struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher builds the card for a URL
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (Card, error)
+}
+
+var (
+	// ErrNotHTML is returned for anything other than an HTML page
+	ErrNotHTML = errors.New("not an HTML page")
+	// ErrNoPreview is returned for pages without even a title
+	ErrNoPreview = errors.New("page has no preview metadata")
+)
+
+const (
+	// DefaultMaxBytes is how much of a page is read looking for metadata,
+	// which lives in the head
+	DefaultMaxBytes = 512 << 10
+	maxRedirects    = 5
+)
+
+// HTTPFetcher fetches pages over HTTP. Links are user-supplied, so it only
+// connects to public addresses: the check runs on the address actually
+// dialed, after DNS resolution and on every redirect, so a hostname can't be
+// pointed at the internal network.
+type HTTPFetcher struct {
+	Client   *http.Client
+	MaxBytes int64
+	allow    func(netip.AddrPort) bool
+}
+
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	f := &HTTPFetcher{
+		MaxBytes: DefaultMaxBytes,
+		allow: func(addrPort netip.AddrPort) bool {
+			return netguard.PublicAddress(addrPort.Addr())
+		},
+	}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			addrPort = netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port())
+			if !f.allow(addrPort) {
+				return fmt.Errorf("%w: %s", netguard.ErrBlockedAddress, addrPort)
+			}
+			return nil
+		},
+	}
+	f.Client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// A proxy from the environment would do the dialing for us,
+			// bypassing the address check
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// Fetch downloads the page and parses its card. The card's URL is where
+// any redirects ended up.
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (Card, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Card{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Card{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Card{}, err
+	}
+	req.Header.Set("User-Agent", "Chirpy-LinkPreview/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return Card{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Card{}, fmt.Errorf("page responded with status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Card{}, fmt.Errorf("%w: %s", ErrNotHTML, mediaType)
+	}
+
+	return Parse(io.LimitReader(resp.Body, f.MaxBytes), resp.Request.URL)
+}
@@ -0,0 +1,129 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/netguard"
+)
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Fallback title</title>
+	<meta property="og:title" content="Chirpy &amp; friends">
+	<meta property="og:description" content="  A   place
+		to chirp ">
+	<meta property="og:image" content="/img/card.png">
+	<meta property="og:site_name" content="Chirpy">
+</head>
+<body><meta property="og:title" content="Not in the head"></body>
+</html>`
+
+// newTestFetcher returns a fetcher allowed to reach httptest servers on
+// loopback
+func newTestFetcher() *HTTPFetcher {
+	f := NewHTTPFetcher(time.Second)
+	f.allow = func(addrPort netip.AddrPort) bool { return addrPort.Addr().IsLoopback() }
+	return f
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := newTestFetcher()
+
+	// Test: OpenGraph tags are parsed, following redirects
+	card, err := f.Fetch(context.Background(), server.URL+"/short")
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+	want := Card{
+		URL:         server.URL + "/page",
+		Title:       "Chirpy & friends",
+		Description: "A place to chirp",
+		ImageURL:    server.URL + "/img/card.png",
+		SiteName:    "Chirpy",
+	}
+	if card != want {
+		t.Errorf("Fetch() = %+v, want %+v", card, want)
+	}
+
+	// Test: Non-HTML responses have no card
+	if _, err := f.Fetch(context.Background(), server.URL+"/image"); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("Expected ErrNotHTML, got %v", err)
+	}
+
+	// Test: Other schemes are refused
+	if _, err := f.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Errorf("Expected error for file URL")
+	}
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	// Test: The default fetcher won't connect to loopback, by IP or by name
+	f := NewHTTPFetcher(time.Second)
+	for _, u := range []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)} {
+		if _, err := f.Fetch(context.Background(), u); !errors.Is(err, netguard.ErrBlockedAddress) {
+			t.Errorf("Fetch(%s) error = %v, want ErrBlockedAddress", u, err)
+		}
+	}
+
+	// Test: Nor will it follow a redirect there
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL, http.StatusFound)
+	}))
+	defer redirector.Close()
+	f = newTestFetcher()
+	f.allow = func(addrPort netip.AddrPort) bool {
+		return addrPort.String() == strings.TrimPrefix(redirector.URL, "http://")
+	}
+	if _, err := f.Fetch(context.Background(), redirector.URL); !errors.Is(err, netguard.ErrBlockedAddress) {
+		t.Errorf("Redirect error = %v, want ErrBlockedAddress", err)
+	}
+
+	if hit {
+		t.Errorf("Blocked server received a request")
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1000) + "<title>Too late</title></head></html>"))
+	}))
+	defer server.Close()
+
+	f := newTestFetcher()
+	f.MaxBytes = 1024
+	if _, err := f.Fetch(context.Background(), server.URL); !errors.Is(err, ErrNoPreview) {
+		t.Errorf("Expected ErrNoPreview past the size limit, got %v", err)
+	}
+}
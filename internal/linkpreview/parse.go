@@ -0,0 +1,108 @@
+package linkpreview
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+)
+
+// Parse reads a card from an HTML page. OpenGraph tags win over the page's
+// title and meta description. base resolves relative image URLs.
+func Parse(r io.Reader, base *url.URL) (Card, error) {
+	meta := map[string]string{}
+	var title strings.Builder
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// io.EOF, or the size limit cut the page short; use what we have
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Body:
+				// Metadata lives in the head
+				break loop
+			case atom.Title:
+				inTitle = tt == html.StartTagToken
+			case atom.Meta:
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				if _, seen := meta[key]; key != "" && !seen {
+					meta[key] = strings.TrimSpace(content)
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				break loop
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		}
+	}
+
+	card := Card{
+		URL:         base.String(),
+		Title:       firstOf(meta["og:title"], meta["twitter:title"], strings.TrimSpace(title.String())),
+		Description: firstOf(meta["og:description"], meta["twitter:description"], meta["description"]),
+		SiteName:    meta["og:site_name"],
+	}
+	if card.Title == "" {
+		return Card{}, ErrNoPreview
+	}
+	card.Title = truncate(strings.Join(strings.Fields(card.Title), " "), maxTitleLength)
+	card.Description = truncate(strings.Join(strings.Fields(card.Description), " "), maxDescriptionLength)
+
+	if image := firstOf(meta["og:image"], meta["twitter:image"]); image != "" {
+		if u, err := base.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			card.ImageURL = u.String()
+		}
+	}
+	return card, nil
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// truncate cuts s to at most n characters, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
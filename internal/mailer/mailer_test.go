@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSMTPMailerTimeout(t *testing.T) {
+	// A relay that accepts connections but never says hello
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	m := &SMTPMailer{Addr: listener.Addr().String(), From: "chirpy@example.com", Timeout: 100 * time.Millisecond}
+	msg := Message{To: "alice@example.com", Subject: "Hi", Body: "Hello"}
+
+	// Test: The timeout ends a stalled conversation
+	start := time.Now()
+	if err := m.Send(context.Background(), msg); err == nil {
+		t.Error("Send() to a hung relay succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send() took %s, want about the 100ms timeout", elapsed)
+	}
+
+	// Test: Cancelling the context ends it sooner than the timeout
+	m.Timeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := m.Send(ctx, msg); err == nil {
+		t.Error("Send() with a cancelled context succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send() took %s after the context was cancelled", elapsed)
+	}
+}
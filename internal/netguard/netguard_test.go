@@ -0,0 +1,30 @@
+package netguard
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"64:ff9b::a00:1":  false,
+		"255.255.255.255": false,
+	}
+	for s, want := range tests {
+		if got := PublicAddress(netip.MustParseAddr(s)); got != want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
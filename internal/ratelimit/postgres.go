@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so that limits
+// hold across every instance sharing the database
+type PostgresStore struct {
+	db *database.Queries
+}
+
+// NewPostgresStore returns a store backed by the given queries
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take removes a token from the bucket for key if one is available
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	row, err := s.db.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:             key,
+		Burst:           float64(limit.Burst),
+		RefillPerSecond: limit.RefillPerSecond(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(row.Allowed, row.Tokens, limit), nil
+}
+
+// Cleanup drops buckets that haven't been touched since before
+func (s *PostgresStore) Cleanup(ctx context.Context, before time.Time) error {
+	return s.db.DeleteStaleRateLimitBuckets(ctx, before)
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket: Burst tokens, refilled evenly over Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// RefillPerSecond is the number of tokens added back to the bucket each second
+func (l Limit) RefillPerSecond() float64 {
+	if l.Period <= 0 {
+		return 0
+	}
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a single token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // time until the next token is available, zero if allowed
+	Reset      time.Duration // time until the bucket is full again
+}
+
+// Store takes tokens from buckets identified by key
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	Cleanup(ctx context.Context, before time.Time) error
+}
+
+// newResult builds a Result from the number of tokens left in a bucket
+func newResult(allowed bool, tokens float64, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+	}
+	rate := limit.RefillPerSecond()
+	if rate <= 0 {
+		return res
+	}
+	res.Reset = time.Duration((float64(limit.Burst) - tokens) / rate * float64(time.Second))
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are only enforced per instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Take removes a token from the bucket for key if one is available
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.RefillPerSecond())
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(allowed, b.tokens, limit), nil
+}
+
+// Cleanup drops buckets that haven't been touched since before
+func (s *MemoryStore) Cleanup(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(before) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	ctx := context.Background()
+
+	// Test: Burst is allowed
+	for i := 0; i < 3; i++ {
+		res, err := store.Take(ctx, "ip:1.2.3.4", limit)
+		if err != nil {
+			t.Fatalf("Take failed: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("Request %d should have been allowed", i+1)
+		}
+		if res.Remaining != 2-i {
+			t.Errorf("Expected %d remaining, got %d", 2-i, res.Remaining)
+		}
+	}
+
+	// Test: Empty bucket is rejected with a retry hint
+	res, _ := store.Take(ctx, "ip:1.2.3.4", limit)
+	if res.Allowed {
+		t.Fatal("Request over the limit was allowed")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("Expected retry after 1s, got %v", res.RetryAfter)
+	}
+
+	// Test: Other keys have their own bucket
+	res, _ = store.Take(ctx, "ip:5.6.7.8", limit)
+	if !res.Allowed {
+		t.Error("Separate key was rate limited")
+	}
+
+	// Test: Tokens refill over time
+	now = now.Add(time.Second)
+	res, _ = store.Take(ctx, "ip:1.2.3.4", limit)
+	if !res.Allowed {
+		t.Error("Request after refill was rejected")
+	}
+
+	// Test: Cleanup removes idle buckets
+	store.Cleanup(ctx, now.Add(time.Minute))
+	if len(store.buckets) != 0 {
+		t.Errorf("Expected no buckets after cleanup, got %d", len(store.buckets))
+	}
+}
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/netguard"
+)
+
+// Headers set on outbound deliveries. Receivers verify them with Verify.
+const (
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+	TimestampHeader = "X-Chirpy-Timestamp"
+	SignatureHeader = "X-Chirpy-Signature"
+)
+
+// maxResponseBytes is how much of a receiver's response is read before the
+// connection is dropped
+const maxResponseBytes = 4 << 10
+
+// Delivery is one signed POST to a receiver
+type Delivery struct {
+	URL       string
+	Secret    string
+	ID        string
+	EventType string
+	Body      []byte
+}
+
+// StatusError is returned when the receiver answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("receiver responded with status %d", e.StatusCode)
+}
+
+// Sender posts signed deliveries. Receiver URLs are chosen by users, so by
+// default it only connects to addresses netguard considers public. AllowPrivate
+// lifts that, for receivers on a local network.
+type Sender struct {
+	Client       *http.Client
+	AllowPrivate bool
+	now          func() time.Time
+}
+
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// Checked at dial time too, so a name that resolved to a public
+		// address at registration can't be rebound to an internal one
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !netguard.PublicAddress(addrPort.Addr().Unmap()) {
+				return fmt.Errorf("%w: %s", netguard.ErrBlockedAddress, addrPort)
+			}
+			return nil
+		},
+	}
+	return &Sender{
+		Client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          50,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			// A redirect would send the payload somewhere the owner didn't register
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		AllowPrivate: allowPrivate,
+		now:          time.Now,
+	}
+}
+
+// CheckURL reports whether deliveries may be sent to rawURL: https to a host
+// that only resolves to public addresses. With AllowPrivate, any http or
+// https URL is accepted.
+func (s *Sender) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("invalid URL %q", rawURL)
+	}
+	if s.AllowPrivate {
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !netguard.PublicAddress(addr.Unmap()) {
+			return fmt.Errorf("%w: %s", netguard.ErrBlockedAddress, addr)
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", netguard.ErrBlockedAddress, host)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !netguard.PublicAddress(addr.Unmap()) {
+			return fmt.Errorf("%w: %s resolves to %s", netguard.ErrBlockedAddress, host, addr)
+		}
+	}
+	return nil
+}
+
+// Send posts the delivery and returns the receiver's status code. Any non-2xx
+// response is a *StatusError.
+func (s *Sender) Send(ctx context.Context, d Delivery) (int, error) {
+	now := s.now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, now, d.Body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff returns how long to wait before the next attempt after the given
+// number of failed attempts: 30s, 1m, 2m, ... capped at 6 hours
+func Backoff(attempts int) time.Duration {
+	const (
+		base = 30 * time.Second
+		max  = 6 * time.Hour
+	)
+	if attempts < 1 {
+		return base
+	}
+	if attempts > 20 {
+		return max
+	}
+	d := base << (attempts - 1)
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/netguard"
+)
+
+func TestSend(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"chirp.created"}`)
+
+	var got *http.Request
+	var gotBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	sender := NewSender(time.Second, true)
+	sender.now = func() time.Time { return now }
+	code, err := sender.Send(context.Background(), Delivery{
+		URL:       receiver.URL,
+		Secret:    "endpoint-secret",
+		ID:        "delivery-1",
+		EventType: "chirp.created",
+		Body:      body,
+	})
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("Send() = %d, %v; want 204, nil", code, err)
+	}
+
+	// Test: Receiver can verify the signature with the endpoint secret
+	err = Verify(
+		[]string{"endpoint-secret"},
+		got.Header.Get(TimestampHeader),
+		got.Header.Get(SignatureHeader),
+		gotBody,
+		5*time.Minute,
+		now,
+	)
+	if err != nil {
+		t.Errorf("Receiver couldn't verify delivery: %v", err)
+	}
+	if got.Header.Get(EventHeader) != "chirp.created" || got.Header.Get(DeliveryHeader) != "delivery-1" {
+		t.Errorf("Unexpected delivery headers: %v", got.Header)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	code, err := NewSender(time.Second, true).Send(context.Background(), Delivery{URL: receiver.URL, Secret: "s"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || code != http.StatusInternalServerError {
+		t.Errorf("Send() = %d, %v; want 500 and a StatusError", code, err)
+	}
+
+	// Test: Redirects are not followed
+	redirect := httptest.NewServer(http.RedirectHandler(receiver.URL, http.StatusFound))
+	defer redirect.Close()
+	code, err = NewSender(time.Second, true).Send(context.Background(), Delivery{URL: redirect.URL, Secret: "s"})
+	if err == nil || code != http.StatusFound {
+		t.Errorf("Send() = %d, %v; want 302 and an error", code, err)
+	}
+}
+
+func TestSenderBlocksPrivateAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	sender := NewSender(time.Second, false)
+
+	// Test: Loopback receivers are refused at registration
+	for _, raw := range []string{receiver.URL, "https://127.0.0.1/hook", "https://localhost/hook", "https://10.0.0.1/hook", "https://169.254.169.254/latest"} {
+		if err := sender.CheckURL(context.Background(), raw); err == nil {
+			t.Errorf("CheckURL(%q) = nil, want an error", raw)
+		}
+	}
+
+	// Test: ...and at dial time, in case the name was rebound since
+	_, err := sender.Send(context.Background(), Delivery{URL: receiver.URL, Secret: "s"})
+	if !errors.Is(err, netguard.ErrBlockedAddress) {
+		t.Errorf("Send() error = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		30: 6 * time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
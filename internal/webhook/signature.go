@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const signatureVersion = "v1"
+
+var (
+	ErrMissingSignature = errors.New("missing signature headers")
+	ErrStaleTimestamp   = errors.New("timestamp outside tolerance window")
+	ErrInvalidSignature = errors.New("no matching signature")
+)
+
+// Sign returns the signature for body sent at t, formatted as "v1=<hex>".
+// The MAC covers "<unix timestamp>.<body>" so a captured payload can't be
+// replayed later with a fresh timestamp.
+func Sign(secret string, t time.Time, body []byte) string {
+	return signatureVersion + "=" + hex.EncodeToString(mac(secret, strconv.FormatInt(t.Unix(), 10), body))
+}
+
+// Verify checks a timestamp and signature header against every active secret,
+// so keys can be rotated without dropping deliveries. The signature header may
+// hold several comma-separated signatures.
+func Verify(secrets []string, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	sentAt := time.Unix(unix, 0)
+	if sentAt.Before(now.Add(-tolerance)) || sentAt.After(now.Add(tolerance)) {
+		return ErrStaleTimestamp
+	}
+
+	for _, candidate := range strings.Split(signature, ",") {
+		version, value, ok := strings.Cut(strings.TrimSpace(candidate), "=")
+		if !ok || version != signatureVersion {
+			continue
+		}
+		given, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		for _, secret := range secrets {
+			if hmac.Equal(given, mac(secret, timestamp, body)) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign("new-key", now, body)
+
+	// Test: Valid signature with one of several active keys
+	if err := Verify([]string{"old-key", "new-key"}, timestamp, signature, body, 5*time.Minute, now); err != nil {
+		t.Errorf("Failed to verify valid signature: %v", err)
+	}
+
+	// Test: Multiple signatures in one header
+	if err := Verify([]string{"new-key"}, timestamp, "v1=deadbeef, "+signature, body, 5*time.Minute, now); err != nil {
+		t.Errorf("Failed to verify header with several signatures: %v", err)
+	}
+
+	// Test: Retired key
+	if err := Verify([]string{"old-key"}, timestamp, signature, body, 5*time.Minute, now); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	// Test: Tampered body
+	if err := Verify([]string{"new-key"}, timestamp, signature, []byte(`{}`), 5*time.Minute, now); err != ErrInvalidSignature {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	// Test: Replayed outside the tolerance window
+	if err := Verify([]string{"new-key"}, timestamp, signature, body, 5*time.Minute, now.Add(10*time.Minute)); err != ErrStaleTimestamp {
+		t.Errorf("Expected ErrStaleTimestamp, got %v", err)
+	}
+
+	// Test: Missing headers
+	if err := Verify([]string{"new-key"}, "", "", body, 5*time.Minute, now); err != ErrMissingSignature {
+		t.Errorf("Expected ErrMissingSignature, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/chirptext"
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/linkpreview"
+	"workspace/github.com/kozykoding/chirpy/internal/netguard"
+
+	"github.com/google/uuid"
+)
+
+const (
+	linkPreviewBatchSize   = 10
+	linkPreviewMaxAttempts = 3
+)
+
+// URLEntity is a link found in a chirp body. Start and End count code
+// points, so clients can slice the body without knowing Go's byte offsets.
+type URLEntity struct {
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	URL         string `json:"url"`
+	ExpandedURL string `json:"expanded_url"`
+	DisplayURL  string `json:"display_url"`
+}
+
+type ChirpEntities struct {
+	URLs []URLEntity `json:"urls"`
+}
+
+// LinkPreview is the card for the first link in a chirp whose page had
+// something to show
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// saveChirpLinks stores the links in a chirp body and queues a preview fetch
+// for each. Any links from a previous version of the body are replaced.
+func saveChirpLinks(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := q.DeleteChirpLinks(ctx, chirpID); err != nil {
+		return err
+	}
+	for i, entity := range chirptext.Entities(body) {
+		err := q.CreateChirpLink(ctx, database.CreateChirpLinkParams{
+			ChirpID:     chirpID,
+			Position:    int32(i),
+			StartIndex:  int32(entity.Start),
+			EndIndex:    int32(entity.End),
+			Url:         entity.URL,
+			ExpandedUrl: entity.ExpandedURL,
+			DisplayUrl:  entity.DisplayURL,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.EnqueueLinkPreview(ctx, entity.ExpandedURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchLinkPreviews builds cards for queued links. Like webhook deliveries,
+// rows are claimed with SKIP LOCKED, and a claim that's never marked expires
+// so the link is tried again.
+func (cfg *apiConfig) fetchLinkPreviews(ctx context.Context) error {
+	previews, err := cfg.db.ClaimLinkPreviews(ctx, linkPreviewBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, preview := range previews {
+		card, fetchErr := cfg.linkPreviews.Fetch(ctx, preview.Url)
+		if fetchErr == nil {
+			err := cfg.db.MarkLinkPreviewFetched(ctx, database.MarkLinkPreviewFetchedParams{
+				Url:         preview.Url,
+				FinalUrl:    card.URL,
+				Title:       card.Title,
+				Description: card.Description,
+				ImageUrl:    card.ImageURL,
+				SiteName:    card.SiteName,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Pages that can't have a preview aren't worth retrying
+		status := "pending"
+		if preview.Attempts >= linkPreviewMaxAttempts ||
+			errors.Is(fetchErr, netguard.ErrBlockedAddress) ||
+			errors.Is(fetchErr, linkpreview.ErrNotHTML) ||
+			errors.Is(fetchErr, linkpreview.ErrNoPreview) {
+			status = "failed"
+		}
+		err := cfg.db.MarkLinkPreviewFailed(ctx, database.MarkLinkPreviewFailedParams{
+			Url:           preview.Url,
+			Status:        status,
+			LastError:     sql.NullString{String: fetchErr.Error(), Valid: true},
+			NextAttemptAt: time.Now().UTC().Add(time.Minute << preview.Attempts),
+		})
+		if err != nil {
+			return err
+		}
+		if status == "failed" {
+			log.Printf("Couldn't build link preview for %s: %s", preview.Url, fetchErr)
+		}
+	}
+	return nil
+}
+
+// linkResponses returns the entities and preview card of each chirp, keyed
+// by chirp ID
+func (cfg *apiConfig) linkResponses(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID][]URLEntity, map[uuid.UUID]LinkPreview, error) {
+	entities := map[uuid.UUID][]URLEntity{}
+	cards := map[uuid.UUID]LinkPreview{}
+	if len(chirpIDs) == 0 {
+		return entities, cards, nil
+	}
+
+	links, err := cfg.db.GetChirpLinks(ctx, chirpIDs)
+	if err != nil || len(links) == 0 {
+		return entities, cards, err
+	}
+	urls := make([]string, 0, len(links))
+	for _, link := range links {
+		entities[link.ChirpID] = append(entities[link.ChirpID], URLEntity{
+			Start:       int(link.StartIndex),
+			End:         int(link.EndIndex),
+			URL:         link.Url,
+			ExpandedURL: link.ExpandedUrl,
+			DisplayURL:  link.DisplayUrl,
+		})
+		urls = append(urls, link.ExpandedUrl)
+	}
+
+	previews, err := cfg.db.GetLinkPreviews(ctx, urls)
+	if err != nil {
+		return nil, nil, err
+	}
+	byURL := map[string]database.LinkPreview{}
+	for _, preview := range previews {
+		byURL[preview.Url] = preview
+	}
+	// Links are ordered by position, so the first card found wins
+	for _, link := range links {
+		if _, ok := cards[link.ChirpID]; ok {
+			continue
+		}
+		preview, ok := byURL[link.ExpandedUrl]
+		if !ok {
+			continue
+		}
+		cards[link.ChirpID] = LinkPreview{
+			URL:         preview.FinalUrl,
+			Title:       preview.Title,
+			Description: preview.Description,
+			ImageURL:    preview.ImageUrl,
+			SiteName:    preview.SiteName,
+		}
+	}
+	return entities, cards, nil
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
+	"time"
 
+	"workspace/github.com/kozykoding/chirpy/internal/activitypub"
 	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/entitlements"
+	"workspace/github.com/kozykoding/chirpy/internal/linkpreview"
+	"workspace/github.com/kozykoding/chirpy/internal/mailer"
+	"workspace/github.com/kozykoding/chirpy/internal/ratelimit"
+	"workspace/github.com/kozykoding/chirpy/internal/webhook"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -16,9 +25,20 @@ import (
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
 	jwtSecret      string
-	polkaKey       string
+	polkaKeys      []string
+	rateLimiter    ratelimit.Store
+	mailer         mailer.Mailer
+	baseURL        string
+	adminAPIKey    string
+	webhookSender  *webhook.Sender
+	entitlements   entitlements.Config
+	linkPreviews   linkpreview.Fetcher
+	federation     *activitypub.Client
+
+	requireVerifiedEmail bool
 }
 
 func main() {
@@ -27,8 +47,14 @@ func main() {
 
 	godotenv.Load()
 	jwtSecret := os.Getenv("JWT_SECRET")
-	polkaKey := os.Getenv("POLKA_KEY")
-	if polkaKey == "" {
+	// POLKA_KEY may hold several comma-separated keys while one is being rotated
+	polkaKeys := []string{}
+	for _, key := range strings.Split(os.Getenv("POLKA_KEY"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			polkaKeys = append(polkaKeys, key)
+		}
+	}
+	if len(polkaKeys) == 0 {
 		log.Fatal("POLKA_KEY environment variable is not set")
 	}
 
@@ -47,35 +73,241 @@ func main() {
 	}
 	dbQueries := database.New(dbConn)
 
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+
+	// MAILER=smtp sends real mail, anything else writes messages to MAIL_LOG_FILE or stdout
+	var mail mailer.Mailer = mailer.NewLogMailer(os.Stdout)
+	if os.Getenv("MAILER") == "smtp" {
+		mail = &mailer.SMTPMailer{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	} else if path := os.Getenv("MAIL_LOG_FILE"); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatalf("Error opening mail log: %s", err)
+		}
+		defer f.Close()
+		mail = mailer.NewLogMailer(f)
+	}
+
+	// RATE_LIMIT_STORE=postgres shares limits across instances
+	var rateLimiter ratelimit.Store = ratelimit.NewMemoryStore()
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		rateLimiter = ratelimit.NewPostgresStore(dbQueries)
+	}
+
+	// ENTITLEMENTS_FILE overrides what each plan unlocks
+	plans := entitlements.Default()
+	if path := os.Getenv("ENTITLEMENTS_FILE"); path != "" {
+		plans, err = entitlements.Load(path)
+		if err != nil {
+			log.Fatalf("Error loading entitlements: %s", err)
+		}
+	}
+
+	// FEDERATION_ALLOW_INSECURE=true federates over plain HTTP and with private
+	// addresses, e.g. between two instances on one machine
+	federation := activitypub.NewClient(10*time.Second, os.Getenv("FEDERATION_ALLOW_INSECURE") == "true")
+
+	// WEBHOOK_ALLOW_PRIVATE=true lets webhook endpoints point at loopback and
+	// private addresses, for receivers running next to the server
+	webhookSender := webhook.NewSender(10*time.Second, os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true")
+
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
 		db:             dbQueries,
+		dbConn:         dbConn,
 		platform:       platform,
 		jwtSecret:      jwtSecret,
-		polkaKey:       polkaKey,
+		polkaKeys:      polkaKeys,
+		rateLimiter:    rateLimiter,
+		mailer:         mail,
+		baseURL:        baseURL,
+		adminAPIKey:    os.Getenv("ADMIN_API_KEY"),
+		webhookSender:  webhookSender,
+		entitlements:   plans,
+		linkPreviews:   linkpreview.NewHTTPFetcher(5 * time.Second),
+		federation:     federation,
+
+		requireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
 	}
 
+	// Rate limit policies, keyed by user ID when authenticated and client IP
+	// otherwise. Authenticated users get their plan's multiplier on top.
+	loginLimit := rateLimitPolicy{
+		name:  "login",
+		limit: ratelimit.Limit{Burst: 5, Period: time.Minute},
+	}
+	signupLimit := rateLimitPolicy{
+		name:  "signup",
+		limit: ratelimit.Limit{Burst: 5, Period: time.Hour},
+	}
+	passwordForgotLimit := rateLimitPolicy{
+		name:  "password_forgot",
+		limit: ratelimit.Limit{Burst: 3, Period: time.Hour},
+	}
+	oauthTokenLimit := rateLimitPolicy{
+		name:  "oauth_token",
+		limit: ratelimit.Limit{Burst: 20, Period: time.Minute},
+	}
+	chirpsCreateLimit := rateLimitPolicy{
+		name:  "chirps_create",
+		limit: ratelimit.Limit{Burst: 10, Period: time.Minute},
+	}
+	polkaWebhookLimit := rateLimitPolicy{
+		name:  "polka_webhook",
+		limit: ratelimit.Limit{Burst: 60, Period: time.Minute},
+	}
+	dataExportLimit := rateLimitPolicy{
+		name:  "data_export",
+		limit: ratelimit.Limit{Burst: 3, Period: time.Hour},
+	}
+
+	ctx := context.Background()
+	go runPeriodically(ctx, "rate_limit_cleanup", 10*time.Minute, func(ctx context.Context) error {
+		return rateLimiter.Cleanup(ctx, time.Now().UTC().Add(-time.Hour))
+	})
+	go runPeriodically(ctx, "login_failures_cleanup", time.Hour, func(ctx context.Context) error {
+		return dbQueries.DeleteStaleLoginFailures(ctx, time.Now().UTC().Add(-24*time.Hour))
+	})
+	go runPeriodically(ctx, "inbound_webhooks_cleanup", time.Hour, func(ctx context.Context) error {
+		// Rejected deliveries are only kept long enough to debug a misconfigured key
+		now := time.Now().UTC()
+		return dbQueries.DeleteInboundWebhooksBefore(ctx, database.DeleteInboundWebhooksBeforeParams{
+			ReceivedBefore: now.Add(-30 * 24 * time.Hour),
+			RejectedBefore: now.Add(-24 * time.Hour),
+		})
+	})
+	go runPeriodically(ctx, "email_outbox", 5*time.Second, apiCfg.deliverPendingEmails)
+	go runPeriodically(ctx, "subscription_expiry", 10*time.Minute, apiCfg.expireLapsedSubscriptions)
+	go runPeriodically(ctx, "webhook_deliveries", 5*time.Second, apiCfg.deliverPendingWebhooks)
+	go runPeriodically(ctx, "scheduled_chirps", 15*time.Second, apiCfg.publishScheduledChirps)
+	go runPeriodically(ctx, "link_previews", 10*time.Second, apiCfg.fetchLinkPreviews)
+	go runPeriodically(ctx, "activity_deliveries", 5*time.Second, apiCfg.deliverActivities)
+	go runPeriodically(ctx, "data_exports", 10*time.Second, apiCfg.buildDataExports)
+	go runPeriodically(ctx, "data_exports_cleanup", time.Hour, dbQueries.DeleteExpiredDataExports)
+	go runPeriodically(ctx, "account_deletions", time.Hour, apiCfg.purgeDeletedAccounts)
+
 	mux := http.NewServeMux()
 	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
 	mux.Handle("/app/", fsHandler)
 
 	mux.HandleFunc("GET /api/healthz", handlerReadiness)
 
-	mux.HandleFunc("POST /api/users", apiCfg.handlerUsersCreate)
+	mux.Handle("POST /api/users", apiCfg.middlewareRateLimit(signupLimit, apiCfg.handlerUsersCreate))
 	mux.HandleFunc("PUT /api/users", apiCfg.handlerUsersUpdate)
+	mux.Handle("DELETE /api/users", apiCfg.middlewareRateLimit(loginLimit, apiCfg.handlerUsersDelete))
+	mux.HandleFunc("GET /api/verify-email", apiCfg.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/users/me/totp", apiCfg.handlerTOTPEnroll)
+	mux.HandleFunc("POST /api/users/me/totp/confirm", apiCfg.handlerTOTPConfirm)
+	mux.HandleFunc("DELETE /api/users/me/totp", apiCfg.handlerTOTPDisable)
+	mux.HandleFunc("GET /api/users/me/subscription", apiCfg.handlerSubscriptionGet)
+	mux.HandleFunc("GET /api/users/me/entitlements", apiCfg.handlerEntitlementsGet)
+	mux.HandleFunc("PUT /api/users/me/privacy", apiCfg.handlerPrivacyUpdate)
+	mux.Handle("POST /api/users/me/export", apiCfg.middlewareRateLimit(dataExportLimit, apiCfg.handlerDataExportCreate))
+	mux.HandleFunc("GET /api/users/me/export", apiCfg.handlerDataExportGet)
+	mux.HandleFunc("GET /api/exports/{exportID}/download", apiCfg.handlerDataExportDownload)
+	mux.HandleFunc("POST /api/tokens", apiCfg.handlerTokensCreate)
+	mux.HandleFunc("GET /api/tokens", apiCfg.handlerTokensGet)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.handlerTokensDelete)
+
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.handlerOAuthClientsCreate)
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.handlerOAuthClientsGet)
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.handlerOAuthClientsDelete)
+	mux.HandleFunc("POST /api/webhooks", apiCfg.handlerWebhookEndpointsCreate)
+	mux.HandleFunc("GET /api/webhooks", apiCfg.handlerWebhookEndpointsGet)
+	mux.HandleFunc("DELETE /api/webhooks/{endpointID}", apiCfg.handlerWebhookEndpointsDelete)
+	mux.HandleFunc("POST /api/webhooks/{endpointID}/enable", apiCfg.handlerWebhookEndpointsEnable)
+	mux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries", apiCfg.handlerWebhookDeliveriesGet)
+	mux.HandleFunc("POST /api/webhooks/{endpointID}/deliveries/{deliveryID}/retry", apiCfg.handlerWebhookDeliveriesRetry)
+	mux.HandleFunc("GET /oauth/authorize", apiCfg.handlerOAuthAuthorizeGet)
+	mux.Handle("POST /oauth/authorize", apiCfg.middlewareRateLimit(loginLimit, apiCfg.handlerOAuthAuthorizePost))
+	mux.Handle("POST /oauth/token", apiCfg.middlewareRateLimit(oauthTokenLimit, apiCfg.handlerOAuthToken))
+	mux.HandleFunc("POST /oauth/revoke", apiCfg.handlerOAuthRevoke)
+	mux.HandleFunc("POST /oauth/introspect", apiCfg.handlerOAuthIntrospect)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerChirpsDelete)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerWebhook)
+	mux.Handle("POST /api/polka/webhooks", apiCfg.middlewareRateLimit(polkaWebhookLimit, apiCfg.handlerWebhook))
 
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
+	mux.Handle("POST /api/login", apiCfg.middlewareRateLimit(loginLimit, apiCfg.handlerLogin))
+	mux.Handle("POST /api/login/mfa", apiCfg.middlewareRateLimit(loginLimit, apiCfg.handlerLoginMFA))
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
+	mux.Handle("POST /api/password/forgot", apiCfg.middlewareRateLimit(passwordForgotLimit, apiCfg.handlerPasswordForgot))
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handlerPasswordReset)
 
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
+	mux.Handle("POST /api/chirps", apiCfg.middlewareRateLimit(chirpsCreateLimit, apiCfg.handlerChirpsCreate))
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsGet)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerChirpsGetSingle)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handlerChirpsUpdate)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/vote", apiCfg.handlerPollVote)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.handlerBookmarksCreate)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.handlerBookmarksDelete)
+	mux.HandleFunc("GET /api/bookmarks", apiCfg.handlerBookmarksGet)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCfg.handlerPinCreate)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.handlerPinDelete)
+	mux.HandleFunc("POST /api/drafts", apiCfg.handlerDraftsCreate)
+	mux.HandleFunc("GET /api/drafts", apiCfg.handlerDraftsGet)
+	mux.HandleFunc("GET /api/drafts/{draftID}", apiCfg.handlerDraftsGetSingle)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.handlerDraftsUpdate)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.handlerDraftsDelete)
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.handlerDraftsPublish)
+	mux.HandleFunc("POST /api/conversations", apiCfg.handlerConversationsCreate)
+	mux.HandleFunc("GET /api/conversations", apiCfg.handlerConversationsGet)
+	mux.HandleFunc("GET /api/conversations/{conversationID}", apiCfg.handlerConversationsGetSingle)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.handlerMessagesCreate)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.handlerMessagesGet)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.handlerConversationsRead)
+	mux.HandleFunc("POST /api/users/{userID}/block", apiCfg.handlerBlocksCreate)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.handlerBlocksDelete)
+	mux.HandleFunc("GET /api/blocks", apiCfg.handlerBlocksGet)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handlerFollowsCreate)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handlerFollowsDelete)
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.handlerFollowersGet)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.handlerFollowingGet)
+	mux.HandleFunc("GET /api/follow-requests", apiCfg.handlerFollowRequestsGet)
+	mux.HandleFunc("POST /api/follow-requests/{userID}/approve", apiCfg.handlerFollowRequestsApprove)
+	mux.HandleFunc("POST /api/follow-requests/{userID}/deny", apiCfg.handlerFollowRequestsDeny)
+	mux.HandleFunc("POST /api/lists", apiCfg.handlerListsCreate)
+	mux.HandleFunc("GET /api/lists", apiCfg.handlerListsGet)
+	mux.HandleFunc("GET /api/lists/followed", apiCfg.handlerListsGetFollowed)
+	mux.HandleFunc("GET /api/users/{userID}/lists", apiCfg.handlerUserListsGet)
+	mux.HandleFunc("GET /api/lists/{listID}", apiCfg.handlerListsGetSingle)
+	mux.HandleFunc("PUT /api/lists/{listID}", apiCfg.handlerListsUpdate)
+	mux.HandleFunc("DELETE /api/lists/{listID}", apiCfg.handlerListsDelete)
+	mux.HandleFunc("GET /api/lists/{listID}/members", apiCfg.handlerListMembersGet)
+	mux.HandleFunc("POST /api/lists/{listID}/members", apiCfg.handlerListMembersCreate)
+	mux.HandleFunc("DELETE /api/lists/{listID}/members/{userID}", apiCfg.handlerListMembersDelete)
+	mux.HandleFunc("GET /api/lists/{listID}/chirps", apiCfg.handlerListChirpsGet)
+	mux.HandleFunc("POST /api/lists/{listID}/follow", apiCfg.handlerListsFollow)
+	mux.HandleFunc("DELETE /api/lists/{listID}/follow", apiCfg.handlerListsUnfollow)
+	mux.HandleFunc("POST /api/remote-follows", apiCfg.handlerRemoteFollowsCreate)
+	mux.HandleFunc("GET /api/remote-follows", apiCfg.handlerRemoteFollowsGet)
+	mux.HandleFunc("DELETE /api/remote-follows/{actorID}", apiCfg.handlerRemoteFollowsDelete)
+	mux.HandleFunc("GET /api/remote-notes", apiCfg.handlerRemoteNotesGet)
+	mux.HandleFunc("GET /users/{userID}/feed.atom", apiCfg.handlerUserFeedAtom)
+	mux.HandleFunc("GET /users/{userID}/feed.rss", apiCfg.handlerUserFeedRSS)
+	mux.HandleFunc("GET /hashtags/{tag}/feed.atom", apiCfg.handlerHashtagFeedAtom)
+	mux.HandleFunc("GET /hashtags/{tag}/feed.rss", apiCfg.handlerHashtagFeedRSS)
+	mux.HandleFunc("GET /.well-known/webfinger", apiCfg.handlerWebFinger)
+	mux.HandleFunc("GET /ap/users/{userID}", apiCfg.handlerActorGet)
+	mux.HandleFunc("GET /ap/users/{userID}/outbox", apiCfg.handlerOutboxGet)
+	mux.HandleFunc("GET /ap/users/{userID}/followers", apiCfg.handlerFollowersCollectionGet)
+	mux.HandleFunc("GET /ap/users/{userID}/following", apiCfg.handlerFollowingCollectionGet)
+	mux.HandleFunc("POST /ap/users/{userID}/inbox", apiCfg.handlerInbox)
+	mux.HandleFunc("POST /ap/inbox", apiCfg.handlerInbox)
+	mux.HandleFunc("GET /ap/chirps/{chirpID}", apiCfg.handlerNoteGet)
 
 	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
+	mux.HandleFunc("GET /admin/webhooks", apiCfg.handlerAdminWebhooksGet)
+	mux.HandleFunc("GET /admin/webhooks/{webhookID}", apiCfg.handlerAdminWebhooksGetSingle)
+	mux.HandleFunc("POST /admin/webhooks/{webhookID}/replay", apiCfg.handlerAdminWebhooksReplay)
 
 	srv := &http.Server{
 		Addr:    ":" + port,
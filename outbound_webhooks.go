@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+	"workspace/github.com/kozykoding/chirpy/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+// Events users can subscribe their webhook endpoints to
+const (
+	eventChirpCreated = "chirp.created"
+	eventChirpDeleted = "chirp.deleted"
+	eventUserFollowed = "user.followed"
+)
+
+var validEventTypes = []string{
+	eventChirpCreated,
+	eventChirpDeleted,
+	eventUserFollowed,
+}
+
+const (
+	webhookDeliveryBatchSize   = 20
+	webhookDeliveryMaxAttempts = 10
+	// An endpoint is disabled after this many failed attempts in a row, across
+	// all of its deliveries
+	webhookEndpointMaxFailures = 25
+)
+
+// webhookPayload is the JSON body of every outbound delivery
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// emitEvent queues a delivery of the event to each of the user's endpoints
+// subscribed to it. Failing to queue doesn't fail the caller's request.
+func (cfg *apiConfig) emitEvent(ctx context.Context, userID uuid.UUID, eventType string, data any) {
+	endpoints, err := cfg.db.ListWebhookEndpointsForEvent(ctx, database.ListWebhookEndpointsForEventParams{
+		UserID:    userID,
+		EventType: eventType,
+	})
+	if err != nil {
+		log.Printf("Couldn't find webhook endpoints for %s: %s", eventType, err)
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	// Every endpoint gets the same event ID, so receivers can dedupe
+	eventID := uuid.New()
+	payload, err := json.Marshal(webhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("Couldn't encode %s event: %s", eventType, err)
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		err := cfg.db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			EndpointID: endpoint.ID,
+			EventID:    eventID,
+			EventType:  eventType,
+			Payload:    payload,
+		})
+		if err != nil {
+			log.Printf("Couldn't queue webhook delivery to %s: %s", endpoint.ID, err)
+		}
+	}
+}
+
+// deliverPendingWebhooks sends due deliveries. Rows are claimed with SKIP
+// LOCKED so several instances can run it at once; a claimed row that is never
+// marked (e.g. the instance died) is retried once its claim expires.
+func (cfg *apiConfig) deliverPendingWebhooks(ctx context.Context) error {
+	deliveries, err := cfg.db.ClaimPendingWebhookDeliveries(ctx, webhookDeliveryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		endpoint, err := cfg.db.GetWebhookEndpoint(ctx, delivery.EndpointID)
+		if err != nil {
+			return err
+		}
+
+		code, sendErr := cfg.webhookSender.Send(ctx, webhook.Delivery{
+			URL:       endpoint.Url,
+			Secret:    endpoint.Secret,
+			ID:        delivery.ID.String(),
+			EventType: delivery.EventType,
+			Body:      delivery.Payload,
+		})
+		responseCode := sql.NullInt32{Int32: int32(code), Valid: code != 0}
+
+		if sendErr == nil {
+			err := cfg.db.MarkWebhookDeliverySucceeded(ctx, database.MarkWebhookDeliverySucceededParams{
+				ID:           delivery.ID,
+				ResponseCode: responseCode,
+			})
+			if err != nil {
+				return err
+			}
+			if err := cfg.db.RecordWebhookEndpointSuccess(ctx, endpoint.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Retry with exponential backoff, dead-lettering once attempts run out
+		status := "pending"
+		if delivery.Attempts >= webhookDeliveryMaxAttempts {
+			status = "dead"
+		}
+		err = cfg.db.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+			ID:            delivery.ID,
+			Status:        status,
+			ResponseCode:  responseCode,
+			LastError:     sql.NullString{String: sendErr.Error(), Valid: true},
+			NextAttemptAt: time.Now().UTC().Add(webhook.Backoff(int(delivery.Attempts))),
+		})
+		if err != nil {
+			return err
+		}
+
+		updated, err := cfg.db.RecordWebhookEndpointFailure(ctx, database.RecordWebhookEndpointFailureParams{
+			MaxFailures: webhookEndpointMaxFailures,
+			ID:          endpoint.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if !endpoint.DisabledAt.Valid && updated.DisabledAt.Valid {
+			cfg.notifyWebhookEndpointDisabled(ctx, updated)
+		}
+	}
+	return nil
+}
+
+// notifyWebhookEndpointDisabled tells the owner their endpoint stopped
+// receiving events
+func (cfg *apiConfig) notifyWebhookEndpointDisabled(ctx context.Context, endpoint database.WebhookEndpoint) {
+	user, err := cfg.db.GetUser(ctx, endpoint.UserID)
+	if err != nil {
+		log.Printf("Couldn't notify owner of disabled webhook endpoint %s: %s", endpoint.ID, err)
+		return
+	}
+	body := fmt.Sprintf(
+		"Your Chirpy webhook endpoint %s failed %d deliveries in a row and has been disabled.\n\n"+
+			"Once it's fixed, re-enable it with POST /api/webhooks/%s/enable. "+
+			"Deliveries that ran out of retries can be retried individually.\n",
+		endpoint.Url, endpoint.ConsecutiveFailures, endpoint.ID,
+	)
+	if err := cfg.enqueueEmail(ctx, user.Email, "Your Chirpy webhook endpoint was disabled", body); err != nil {
+		log.Printf("Couldn't queue webhook disabled email: %s", err)
+	}
+}
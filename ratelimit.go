@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/ratelimit"
+)
+
+type rateLimitPolicy struct {
+	name  string
+	limit ratelimit.Limit
+}
+
+func (cfg *apiConfig) middlewareRateLimit(policy rateLimitPolicy, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, limit := cfg.rateLimitKey(r, policy)
+
+		res, err := cfg.rateLimiter.Take(r.Context(), policy.name+":"+key, limit)
+		if err != nil {
+			// Fail open: a broken limiter shouldn't take the API down with it
+			log.Printf("Rate limiter error: %s", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey identifies the caller by user ID when the request carries a
+// valid access token or personal access token, falling back to the client IP
+func (cfg *apiConfig) rateLimitKey(r *http.Request, policy rateLimitPolicy) (string, ratelimit.Limit) {
+	info, err := cfg.authenticateRequest(r)
+	if err != nil {
+		return "ip:" + clientIP(r), policy.limit
+	}
+
+	// Higher plans get proportionally more requests over the same period
+	limit := policy.limit
+	if caps, err := cfg.capabilitiesFor(r.Context(), info.userID); err == nil {
+		limit.Burst = int(math.Round(float64(limit.Burst) * caps.RateLimitMultiplier))
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+	}
+	return "user:" + info.userID.String(), limit
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
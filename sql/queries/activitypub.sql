@@ -0,0 +1,171 @@
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys (user_id, created_at, public_key_pem, private_key_pem)
+VALUES ($1, NOW(), $2, $3)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: GetActorKey :one
+SELECT * FROM actor_keys
+WHERE user_id = $1;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors (id, created_at, updated_at, actor_url, inbox_url, shared_inbox_url, key_id, public_key_pem, username)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6)
+ON CONFLICT (actor_url) DO UPDATE
+SET inbox_url = EXCLUDED.inbox_url,
+    shared_inbox_url = EXCLUDED.shared_inbox_url,
+    key_id = EXCLUDED.key_id,
+    public_key_pem = EXCLUDED.public_key_pem,
+    username = EXCLUDED.username,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetRemoteActorByURL :one
+SELECT * FROM remote_actors
+WHERE actor_url = $1;
+
+-- name: GetRemoteActorByKeyID :one
+SELECT * FROM remote_actors
+WHERE key_id = $1;
+
+-- name: GetRemoteActor :one
+SELECT * FROM remote_actors
+WHERE id = $1;
+
+-- name: CreateRemoteFollower :exec
+INSERT INTO remote_followers (remote_actor_id, user_id, created_at, activity_id)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (remote_actor_id, user_id) DO UPDATE
+SET activity_id = EXCLUDED.activity_id;
+
+-- name: DeleteRemoteFollowerByActivity :execrows
+DELETE FROM remote_followers
+WHERE remote_actor_id = $1 AND activity_id = $2;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*) FROM remote_followers
+WHERE user_id = $1;
+
+-- name: GetRemoteFollowerInboxes :many
+SELECT DISTINCT remote_actors.shared_inbox_url FROM remote_followers
+JOIN remote_actors ON remote_actors.id = remote_followers.remote_actor_id
+WHERE remote_followers.user_id = $1;
+
+-- name: CreateRemoteFollowing :execrows
+INSERT INTO remote_following (user_id, remote_actor_id, created_at, activity_id)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (user_id, remote_actor_id) DO NOTHING;
+
+-- name: AcceptRemoteFollowing :execrows
+UPDATE remote_following
+SET accepted_at = NOW()
+WHERE remote_actor_id = $1 AND activity_id = $2 AND accepted_at IS NULL;
+
+-- name: RejectRemoteFollowing :execrows
+DELETE FROM remote_following
+WHERE remote_actor_id = $1 AND activity_id = $2;
+
+-- name: DeleteRemoteFollowing :one
+DELETE FROM remote_following
+WHERE user_id = $1 AND remote_actor_id = $2
+RETURNING *;
+
+-- name: ListRemoteFollowing :many
+SELECT remote_following.*, remote_actors.actor_url, remote_actors.username FROM remote_following
+JOIN remote_actors ON remote_actors.id = remote_following.remote_actor_id
+WHERE remote_following.user_id = $1
+ORDER BY remote_following.created_at DESC;
+
+-- name: CountRemoteFollowing :one
+SELECT COUNT(*) FROM remote_following
+WHERE user_id = $1 AND accepted_at IS NOT NULL;
+
+-- name: IsRemoteActorFollowed :one
+SELECT EXISTS (
+    SELECT 1 FROM remote_following
+    WHERE remote_actor_id = $1 AND accepted_at IS NOT NULL
+);
+
+-- name: CreateRemoteNote :exec
+INSERT INTO remote_notes (id, created_at, remote_actor_id, object_id, url, content, published_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5)
+ON CONFLICT (object_id) DO NOTHING;
+
+-- name: DeleteRemoteNote :exec
+DELETE FROM remote_notes
+WHERE remote_actor_id = $1 AND object_id = $2;
+
+-- name: ListRemoteNotesForUser :many
+SELECT remote_notes.*, remote_actors.actor_url, remote_actors.username FROM remote_notes
+JOIN remote_actors ON remote_actors.id = remote_notes.remote_actor_id
+JOIN remote_following ON remote_following.remote_actor_id = remote_notes.remote_actor_id
+WHERE remote_following.user_id = $1
+AND remote_following.accepted_at IS NOT NULL
+ORDER BY remote_notes.published_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CreateRemoteLike :exec
+INSERT INTO remote_likes (remote_actor_id, chirp_id, created_at, activity_id)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (remote_actor_id, chirp_id) DO UPDATE
+SET activity_id = EXCLUDED.activity_id;
+
+-- name: DeleteRemoteLikeByActivity :execrows
+DELETE FROM remote_likes
+WHERE remote_actor_id = $1 AND activity_id = $2;
+
+-- name: ListRemoteLikesForUser :many
+SELECT remote_likes.chirp_id, remote_actors.actor_url, remote_likes.created_at FROM remote_likes
+JOIN chirps ON chirps.id = remote_likes.chirp_id
+JOIN remote_actors ON remote_actors.id = remote_likes.remote_actor_id
+WHERE chirps.user_id = $1
+ORDER BY remote_likes.created_at DESC;
+
+-- name: GetRemoteLikeCounts :many
+SELECT chirp_id, COUNT(*) AS likes FROM remote_likes
+WHERE chirp_id = ANY(@chirp_ids::uuid[])
+GROUP BY chirp_id;
+
+-- name: CreateActivityDeliveries :exec
+INSERT INTO activity_deliveries (id, created_at, updated_at, user_id, inbox_url, payload, status, attempts, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), @user_id, unnest(@inbox_urls::text[]), @payload, 'pending', 0, NOW();
+
+-- name: ClaimActivityDeliveries :many
+UPDATE activity_deliveries
+SET attempts = attempts + 1,
+    next_attempt_at = NOW() + INTERVAL '5 minutes',
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM activity_deliveries
+    WHERE status = 'pending'
+    AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkActivityDeliveryDelivered :exec
+UPDATE activity_deliveries
+SET status = 'delivered',
+    delivered_at = NOW(),
+    last_error = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkActivityDeliveryFailed :exec
+UPDATE activity_deliveries
+SET status = $2,
+    last_error = $3,
+    next_attempt_at = $4,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ClaimDueActorDeletions :many
+INSERT INTO actor_deletions (user_id, created_at)
+SELECT id, NOW() FROM users
+WHERE deletion_scheduled_at <= NOW()
+AND id NOT IN (SELECT user_id FROM actor_deletions)
+ORDER BY deletion_scheduled_at
+LIMIT @batch_size::int
+ON CONFLICT (user_id) DO NOTHING
+RETURNING user_id;
@@ -0,0 +1,21 @@
+-- name: CreateBookmark :execrows
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListBookmarkedChirps :many
+SELECT chirps.* FROM chirps
+JOIN bookmarks ON bookmarks.chirp_id = chirps.id
+WHERE bookmarks.user_id = $1
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $1)
+ORDER BY bookmarks.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetBookmarkedChirpIDs :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id = @user_id
+AND chirp_id = ANY(@chirp_ids::uuid[]);
@@ -1,16 +1,18 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, visibility)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT * FROM chirps
+WHERE chirp_visible_to(id, user_id, visibility, @viewer_id)
 ORDER BY created_at ASC;
 
 -- name: GetChirp :one
@@ -20,7 +22,42 @@ WHERE id = $1;
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE id = $1 AND user_id = $2;
 
+-- name: GetVisibleChirp :one
+SELECT * FROM chirps
+WHERE id = @id
+AND chirp_visible_to(id, user_id, visibility, @viewer_id);
+
 -- name: GetChirpsByAuthor :many
 SELECT * FROM chirps
-WHERE user_id = $1
+WHERE user_id = @user_id
+AND chirp_visible_to(id, user_id, visibility, @viewer_id)
 ORDER BY created_at ASC;
+
+-- name: GetLatestChirpsByAuthor :many
+SELECT * FROM chirps
+WHERE user_id = @user_id
+AND chirp_visible_to(id, user_id, visibility, @viewer_id)
+ORDER BY created_at DESC, id DESC
+LIMIT @max_results OFFSET @page_offset;
+
+-- name: CountChirpsByAuthor :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = @user_id
+AND chirp_visible_to(id, user_id, visibility, @viewer_id);
+
+-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $3,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: CreateChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT @chirp_id, id FROM users
+WHERE id = ANY(@user_ids::uuid[])
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: GetChirpMentions :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(@chirp_ids::uuid[]);
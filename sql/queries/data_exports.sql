@@ -0,0 +1,53 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id, status, attempts, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, 'pending', 0, NOW())
+RETURNING id, created_at, status, size_bytes, completed_at, expires_at;
+
+-- name: GetLatestDataExport :one
+SELECT id, created_at, status, size_bytes, completed_at, expires_at FROM data_exports
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetDataExportArchive :one
+SELECT archive FROM data_exports
+WHERE id = $1
+AND status = 'ready'
+AND expires_at > NOW();
+
+-- name: ClaimDataExports :many
+UPDATE data_exports
+SET attempts = attempts + 1,
+    next_attempt_at = NOW() + INTERVAL '10 minutes',
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+    AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, attempts;
+
+-- name: MarkDataExportReady :exec
+UPDATE data_exports
+SET status = 'ready',
+    archive = @archive,
+    size_bytes = @size_bytes,
+    completed_at = NOW(),
+    expires_at = @expires_at,
+    updated_at = NOW()
+WHERE id = @id;
+
+-- name: MarkDataExportFailed :exec
+UPDATE data_exports
+SET status = @status,
+    last_error = @last_error,
+    next_attempt_at = @next_attempt_at,
+    expires_at = @expires_at,
+    updated_at = NOW()
+WHERE id = @id;
+
+-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports WHERE expires_at <= NOW();
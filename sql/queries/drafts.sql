@@ -0,0 +1,50 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, publish_at, visibility, mentions)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListDrafts :many
+SELECT * FROM drafts
+WHERE user_id = $1
+ORDER BY publish_at ASC NULLS LAST, created_at DESC;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET body = $3,
+    publish_at = $4,
+    visibility = $5,
+    mentions = $6,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM drafts
+WHERE id = $1 AND user_id = $2;
+
+-- name: TakeDraft :one
+DELETE FROM drafts
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: ClaimDueDrafts :many
+SELECT drafts.* FROM drafts
+JOIN users ON users.id = drafts.user_id
+WHERE drafts.publish_at <= NOW()
+AND users.deletion_scheduled_at IS NULL
+ORDER BY drafts.publish_at
+LIMIT @batch_size::int
+FOR UPDATE OF drafts SKIP LOCKED;
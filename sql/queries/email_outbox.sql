@@ -0,0 +1,44 @@
+-- name: EnqueueEmail :one
+INSERT INTO email_outbox (id, created_at, updated_at, to_address, subject, body, attempts, next_attempt_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    0,
+    NOW()
+)
+RETURNING *;
+
+-- name: ClaimPendingEmails :many
+UPDATE email_outbox
+SET attempts = attempts + 1,
+    next_attempt_at = NOW() + INTERVAL '5 minutes',
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM email_outbox
+    WHERE status = 'pending'
+    AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkEmailSent :exec
+UPDATE email_outbox
+SET status = 'sent',
+    sent_at = NOW(),
+    last_error = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkEmailFailed :exec
+UPDATE email_outbox
+SET status = $2,
+    last_error = $3,
+    next_attempt_at = $4,
+    updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,35 @@
+-- name: CreateFollowRequest :execrows
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (requester_id, target_id) DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE requester_id = $1 AND target_id = $2;
+
+-- name: TakeFollowRequest :one
+DELETE FROM follow_requests
+WHERE requester_id = $1 AND target_id = $2
+RETURNING *;
+
+-- name: DeleteFollowRequestsBetween :exec
+DELETE FROM follow_requests
+WHERE (requester_id = @user_a AND target_id = @user_b)
+OR (requester_id = @user_b AND target_id = @user_a);
+
+-- name: ListFollowRequests :many
+SELECT * FROM follow_requests
+WHERE target_id = $1
+ORDER BY created_at
+LIMIT $2 OFFSET $3;
+
+-- name: ApproveAllFollowRequests :many
+WITH approved AS (
+    DELETE FROM follow_requests
+    WHERE target_id = $1
+    RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT requester_id, target_id, NOW() FROM approved
+ON CONFLICT (follower_id, followee_id) DO NOTHING
+RETURNING follower_id;
@@ -0,0 +1,31 @@
+-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: DeleteFollow :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = @user_a AND followee_id = @user_b)
+OR (follower_id = @user_b AND followee_id = @user_a);
+
+-- name: IsFollowing :one
+SELECT EXISTS (
+    SELECT 1 FROM follows
+    WHERE follower_id = $1 AND followee_id = $2
+);
+
+-- name: ListFollowers :many
+SELECT * FROM follows
+WHERE followee_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListFollowing :many
+SELECT * FROM follows
+WHERE follower_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
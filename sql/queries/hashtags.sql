@@ -0,0 +1,16 @@
+-- name: CreateChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag)
+SELECT @chirp_id, unnest(@tags::text[])
+ON CONFLICT (chirp_id, tag) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: GetChirpsByHashtag :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = @tag
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, @viewer_id)
+ORDER BY chirps.created_at DESC
+LIMIT @max_results;
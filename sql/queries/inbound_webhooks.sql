@@ -0,0 +1,36 @@
+-- name: CreateInboundWebhook :one
+INSERT INTO inbound_webhooks (id, received_at, headers, body, replay_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: FinishInboundWebhook :exec
+UPDATE inbound_webhooks
+SET verified = $2,
+    verification_error = $3,
+    event_id = $4,
+    event = $5,
+    outcome = $6,
+    response_code = $7,
+    error = $8,
+    processed_at = NOW()
+WHERE id = $1;
+
+-- name: GetInboundWebhook :one
+SELECT * FROM inbound_webhooks WHERE id = $1;
+
+-- name: ListInboundWebhooks :many
+SELECT * FROM inbound_webhooks
+WHERE (@outcome::text = '' OR outcome = @outcome::text)
+ORDER BY received_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: DeleteInboundWebhooksBefore :exec
+DELETE FROM inbound_webhooks
+WHERE received_at < @received_before
+OR (NOT verified AND received_at < @rejected_before);
@@ -0,0 +1,64 @@
+-- name: CreateChirpLink :exec
+INSERT INTO chirp_links (chirp_id, position, start_index, end_index, url, expanded_url, display_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: DeleteChirpLinks :exec
+DELETE FROM chirp_links
+WHERE chirp_id = $1;
+
+-- name: GetChirpLinks :many
+SELECT * FROM chirp_links
+WHERE chirp_id = ANY(@chirp_ids::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: EnqueueLinkPreview :exec
+INSERT INTO link_previews (url, created_at, updated_at, status, attempts, next_attempt_at)
+VALUES ($1, NOW(), NOW(), 'pending', 0, NOW())
+ON CONFLICT (url) DO UPDATE
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = NOW(),
+    updated_at = NOW()
+WHERE link_previews.status <> 'pending'
+AND link_previews.updated_at < NOW() - INTERVAL '7 days';
+
+-- name: ClaimLinkPreviews :many
+UPDATE link_previews
+SET attempts = attempts + 1,
+    next_attempt_at = NOW() + INTERVAL '2 minutes',
+    updated_at = NOW()
+WHERE url IN (
+    SELECT url FROM link_previews
+    WHERE status = 'pending'
+    AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkLinkPreviewFetched :exec
+UPDATE link_previews
+SET status = 'fetched',
+    fetched_at = NOW(),
+    updated_at = NOW(),
+    last_error = NULL,
+    final_url = $2,
+    title = $3,
+    description = $4,
+    image_url = $5,
+    site_name = $6
+WHERE url = $1;
+
+-- name: MarkLinkPreviewFailed :exec
+UPDATE link_previews
+SET status = $2,
+    last_error = $3,
+    next_attempt_at = $4,
+    updated_at = NOW()
+WHERE url = $1;
+
+-- name: GetLinkPreviews :many
+SELECT * FROM link_previews
+WHERE url = ANY(@urls::text[])
+AND status = 'fetched';
@@ -0,0 +1,81 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, user_id, name, description, is_private)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetList :one
+SELECT * FROM lists
+WHERE id = $1;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $3,
+    description = $4,
+    is_private = $5,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteList :execrows
+DELETE FROM lists
+WHERE id = $1 AND user_id = $2;
+
+-- name: CountListsByOwner :one
+SELECT COUNT(*) FROM lists
+WHERE user_id = $1;
+
+-- name: ListListsByOwner :many
+SELECT * FROM lists
+WHERE user_id = @user_id
+AND (@include_private::bool OR NOT is_private)
+ORDER BY created_at DESC;
+
+-- name: ListFollowedLists :many
+SELECT lists.* FROM lists
+JOIN list_follows ON list_follows.list_id = lists.id
+WHERE list_follows.user_id = $1
+AND NOT lists.is_private
+ORDER BY list_follows.created_at DESC;
+
+-- name: AddListMember :execrows
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (list_id, user_id) DO NOTHING;
+
+-- name: RemoveListMember :execrows
+DELETE FROM list_members
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
+
+-- name: ListListMembers :many
+SELECT * FROM list_members
+WHERE list_id = $1
+ORDER BY created_at;
+
+-- name: FollowList :execrows
+INSERT INTO list_follows (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (list_id, user_id) DO NOTHING;
+
+-- name: UnfollowList :execrows
+DELETE FROM list_follows
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: GetListChirps :many
+SELECT chirps.* FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+WHERE list_members.list_id = @list_id
+AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, @viewer_id)
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
@@ -0,0 +1,22 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures WHERE key = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (key, failures, locked_until, updated_at)
+VALUES ($1, 1, NULL, NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_failures.updated_at < NOW() - INTERVAL '24 hours' THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: SetLoginLockout :exec
+UPDATE login_failures SET locked_until = $2 WHERE key = $1;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures WHERE key = $1;
+
+-- name: DeleteStaleLoginFailures :exec
+DELETE FROM login_failures WHERE updated_at < $1;
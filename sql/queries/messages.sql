@@ -0,0 +1,119 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW());
+
+-- name: FindDirectConversation :one
+SELECT * FROM conversations
+WHERE NOT is_group
+AND id IN (
+    SELECT conversation_id FROM conversation_participants
+    GROUP BY conversation_id
+    HAVING bool_or(user_id = @user_a) AND bool_or(user_id = @user_b)
+)
+LIMIT 1;
+
+-- name: LockDirectConversation :exec
+SELECT pg_advisory_xact_lock(hashtextextended(@pair_key::text, 0));
+
+-- name: GetConversationForUser :one
+SELECT conversations.* FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversations.id = $1
+AND conversation_participants.user_id = $2;
+
+-- name: ListConversationsForUser :many
+SELECT conversations.* FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = $1
+ORDER BY conversations.updated_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListConversationParticipants :many
+SELECT * FROM conversation_participants
+WHERE conversation_id = ANY(@conversation_ids::uuid[])
+ORDER BY joined_at;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: ListLatestMessages :many
+SELECT * FROM messages
+WHERE conversation_id = @conversation_id
+AND sender_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = @viewer_id)
+ORDER BY created_at DESC, id DESC
+LIMIT @max_results::int;
+
+-- name: ListMessagesBefore :many
+SELECT * FROM messages
+WHERE conversation_id = @conversation_id
+AND sender_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = @viewer_id)
+AND (created_at, id) < (SELECT m.created_at, m.id FROM messages m WHERE m.id = @cursor_id)
+ORDER BY created_at DESC, id DESC
+LIMIT @max_results::int;
+
+-- name: ListMessagesAfter :many
+SELECT * FROM messages
+WHERE conversation_id = @conversation_id
+AND sender_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = @viewer_id)
+AND (created_at, id) > (SELECT m.created_at, m.id FROM messages m WHERE m.id = @cursor_id)
+ORDER BY created_at ASC, id ASC
+LIMIT @max_results::int;
+
+-- name: GetLastMessages :many
+SELECT DISTINCT ON (conversation_id) * FROM messages
+WHERE conversation_id = ANY(@conversation_ids::uuid[])
+AND sender_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = @viewer_id)
+ORDER BY conversation_id, created_at DESC, id DESC;
+
+-- name: CountUnreadMessages :many
+SELECT p.conversation_id, COUNT(m.id) AS unread
+FROM conversation_participants p
+JOIN messages m ON m.conversation_id = p.conversation_id
+LEFT JOIN messages lr ON lr.id = p.last_read_message_id
+WHERE p.user_id = @user_id
+AND m.sender_id <> p.user_id
+AND m.sender_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = @user_id)
+AND (lr.id IS NULL OR (m.created_at, m.id) > (lr.created_at, lr.id))
+GROUP BY p.conversation_id;
+
+-- name: MarkConversationRead :execrows
+UPDATE conversation_participants p
+SET last_read_message_id = m.id,
+    last_read_at = NOW()
+FROM messages m
+WHERE p.conversation_id = @conversation_id
+AND p.user_id = @user_id
+AND m.id = @message_id
+AND m.conversation_id = p.conversation_id
+AND (
+    p.last_read_message_id IS NULL
+    OR (m.created_at, m.id) > (SELECT lr.created_at, lr.id FROM messages lr WHERE lr.id = p.last_read_message_id)
+);
+
+-- name: GetMessageInConversation :one
+SELECT * FROM messages
+WHERE id = $1 AND conversation_id = $2;
@@ -0,0 +1,50 @@
+-- name: CreatePoll :one
+INSERT INTO polls (id, created_at, chirp_id, closes_at, hide_results)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: CreatePollOption :one
+INSERT INTO poll_options (id, poll_id, position, text)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetPollByChirp :one
+SELECT * FROM polls WHERE chirp_id = $1;
+
+-- name: GetPollsByChirps :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(@chirp_ids::uuid[]);
+
+-- name: GetPollOptionTallies :many
+SELECT o.id, o.poll_id, o.position, o.text, COUNT(v.user_id) AS votes
+FROM poll_options o
+LEFT JOIN poll_votes v ON v.option_id = o.id
+WHERE o.poll_id = ANY(@poll_ids::uuid[])
+GROUP BY o.id
+ORDER BY o.poll_id, o.position;
+
+-- name: GetUserPollVotes :many
+SELECT * FROM poll_votes
+WHERE user_id = @user_id
+AND poll_id = ANY(@poll_ids::uuid[]);
+
+-- name: ListPollVotesForUser :many
+SELECT * FROM poll_votes
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (poll_id, user_id, option_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (poll_id, user_id) DO NOTHING;
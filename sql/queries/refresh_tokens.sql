@@ -14,4 +14,37 @@ SELECT users.* FROM users
 JOIN refresh_tokens ON users.id = refresh_tokens.user_id
 WHERE refresh_tokens.token = $1
 AND refresh_tokens.expires_at > NOW()
-AND refresh_tokens.revoked_at IS NULL;
+AND refresh_tokens.revoked_at IS NULL
+AND refresh_tokens.client_id IS NULL;
+
+-- name: RevokeAllRefreshTokensForUser :exec
+UPDATE refresh_tokens SET revoked_at = NOW(), updated_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes)
+VALUES ($1, NOW(), NOW(), $2, $3, NULL, $4, $5)
+RETURNING *;
+
+-- name: GetOAuthRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token = $1
+AND client_id = $2
+AND expires_at > NOW()
+AND revoked_at IS NULL;
+
+-- name: RevokeOAuthRefreshToken :exec
+UPDATE refresh_tokens SET revoked_at = NOW(), updated_at = NOW() WHERE token = $1 AND client_id = $2;
+
+-- name: UseOAuthRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE token = $1
+AND client_id = $2
+AND revoked_at IS NULL
+AND expires_at > NOW()
+RETURNING *;
+
+-- name: ListRefreshTokenSessions :many
+SELECT created_at, expires_at, revoked_at, client_id FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
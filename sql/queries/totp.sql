@@ -0,0 +1,35 @@
+-- name: UpsertUserTOTP :one
+INSERT INTO user_totp (user_id, secret, created_at, confirmed_at, last_used_step)
+VALUES ($1, $2, NOW(), NULL, 0)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    created_at = NOW(),
+    confirmed_at = NULL,
+    last_used_step = 0
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp WHERE user_id = $1;
+
+-- name: ConfirmUserTOTP :exec
+UPDATE user_totp SET confirmed_at = NOW() WHERE user_id = $1;
+
+-- name: SetTOTPLastUsedStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, user_id, code_hash, created_at, used_at)
+VALUES (gen_random_uuid(), $1, $2, NOW(), NULL);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
@@ -0,0 +1,27 @@
+-- name: CreateUserBlock :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING;
+
+-- name: DeleteUserBlock :execrows
+DELETE FROM user_blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: ListBlockedUsers :many
+SELECT * FROM user_blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = @user_a AND blocked_id = @user_b)
+    OR (blocker_id = @user_b AND blocked_id = @user_a)
+);
+
+-- name: IsBlockedAmong :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE blocker_id = ANY(@user_ids::uuid[])
+    AND blocked_id = ANY(@user_ids::uuid[])
+);
@@ -20,12 +20,75 @@ SELECT * FROM users WHERE email = $1;
 UPDATE users
 SET email = $2,
     hashed_password = $3,
+    email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END,
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
--- name: UpgradeUserToChirpyRed :one
+-- name: SetUserChirpyRed :one
 UPDATE users
-SET is_chirpy_red = true
+SET is_chirpy_red = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkEmailVerified :one
+UPDATE users
+SET email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetPinnedChirp :one
+UPDATE users
+SET pinned_chirp_id = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserPrivate :one
+UPDATE users
+SET is_private = $2,
+    updated_at = NOW()
 WHERE id = $1
 RETURNING *;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_scheduled_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+AND deletion_scheduled_at IS NOT NULL
+AND id NOT IN (SELECT user_id FROM actor_deletions);
+
+-- name: PurgeDueUsers :many
+DELETE FROM users
+WHERE id IN (
+    SELECT id FROM users
+    JOIN actor_deletions ON actor_deletions.user_id = users.id
+    WHERE deletion_scheduled_at <= NOW()
+    AND (
+        actor_deletions.created_at <= NOW() - INTERVAL '1 day'
+        OR NOT EXISTS (
+            SELECT 1 FROM activity_deliveries
+            WHERE activity_deliveries.user_id = users.id AND status = 'pending'
+        )
+    )
+    ORDER BY deletion_scheduled_at
+    LIMIT @batch_size::int
+    FOR UPDATE OF users SKIP LOCKED
+)
+RETURNING id;
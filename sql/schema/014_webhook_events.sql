@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE webhook_events (
+    event_id TEXT PRIMARY KEY,
+    event TEXT NOT NULL,
+    received_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webhook_events;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE inbound_webhooks (
+    id UUID PRIMARY KEY,
+    received_at TIMESTAMP NOT NULL,
+    headers JSONB NOT NULL,
+    body BYTEA NOT NULL,
+    replay_of UUID REFERENCES inbound_webhooks(id) ON DELETE SET NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    verification_error TEXT,
+    event_id TEXT,
+    event TEXT,
+    outcome TEXT NOT NULL DEFAULT 'received',
+    response_code INTEGER,
+    error TEXT,
+    processed_at TIMESTAMP
+);
+
+CREATE INDEX inbound_webhooks_received_at_idx ON inbound_webhooks (received_at DESC);
+CREATE INDEX inbound_webhooks_event_id_idx ON inbound_webhooks (event_id);
+
+-- +goose Down
+DROP TABLE inbound_webhooks;
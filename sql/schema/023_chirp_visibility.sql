@@ -0,0 +1,63 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'followers', 'mentioned'));
+
+CREATE TABLE follows (
+    follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id);
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- Drafts keep their audience until they're published
+ALTER TABLE drafts
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'followers', 'mentioned')),
+ADD COLUMN mentions UUID[] NOT NULL DEFAULT '{}';
+
+-- chirp_visible_to is the single definition of who may read a chirp. Every
+-- read path filters with it. The author and mentioned users always see a
+-- chirp; followers-only chirps are also visible to the author's followers.
+-- A nil viewer ID (not logged in) only sees public chirps.
+-- +goose StatementBegin
+CREATE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT visibility = 'public'
+        OR author_id = viewer_id
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions m
+            WHERE m.chirp_id = chirp_visible_to.chirp_id AND m.user_id = viewer_id
+        )
+        OR (visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows f
+            WHERE f.follower_id = viewer_id AND f.followee_id = author_id
+        ));
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_visible_to(UUID, UUID, TEXT, UUID);
+
+ALTER TABLE drafts
+DROP COLUMN mentions,
+DROP COLUMN visibility;
+
+DROP TABLE chirp_mentions;
+DROP TABLE follows;
+
+ALTER TABLE chirps
+DROP COLUMN visibility;
@@ -0,0 +1,60 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE follow_requests (
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (requester_id, target_id),
+    CHECK (requester_id <> target_id)
+);
+
+CREATE INDEX follow_requests_target_id_idx ON follow_requests (target_id, created_at);
+
+-- A private account's chirps are only visible to approved followers (and
+-- users it mentions), whatever their visibility
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT author_id = viewer_id
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions m
+            WHERE m.chirp_id = chirp_visible_to.chirp_id AND m.user_id = viewer_id
+        )
+        OR (visibility = 'public' AND NOT EXISTS (
+            SELECT 1 FROM users u
+            WHERE u.id = author_id AND u.is_private
+        ))
+        OR (visibility IN ('public', 'followers') AND EXISTS (
+            SELECT 1 FROM follows f
+            WHERE f.follower_id = viewer_id AND f.followee_id = author_id
+        ));
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT visibility = 'public'
+        OR author_id = viewer_id
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions m
+            WHERE m.chirp_id = chirp_visible_to.chirp_id AND m.user_id = viewer_id
+        )
+        OR (visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows f
+            WHERE f.follower_id = viewer_id AND f.followee_id = author_id
+        ));
+$$;
+-- +goose StatementEnd
+
+DROP TABLE follow_requests;
+
+ALTER TABLE users
+DROP COLUMN is_private;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE chirp_links (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    start_index INTEGER NOT NULL,
+    end_index INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    expanded_url TEXT NOT NULL,
+    display_url TEXT NOT NULL,
+    PRIMARY KEY (chirp_id, position)
+);
+
+-- Preview cards are cached per URL, shared by every chirp linking to it
+CREATE TABLE link_previews (
+    url TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'fetched', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT,
+    fetched_at TIMESTAMP,
+    final_url TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX link_previews_pending_idx ON link_previews (next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE link_previews;
+DROP TABLE chirp_links;
@@ -0,0 +1,93 @@
+-- +goose Up
+-- Each user's actor key pair, created the first time it's needed
+CREATE TABLE actor_keys (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL
+);
+
+-- Actors on other servers, cached from their actor documents
+CREATE TABLE remote_actors (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    actor_url TEXT NOT NULL UNIQUE,
+    inbox_url TEXT NOT NULL,
+    shared_inbox_url TEXT NOT NULL,
+    key_id TEXT NOT NULL UNIQUE,
+    public_key_pem TEXT NOT NULL,
+    username TEXT NOT NULL
+);
+
+-- Remote actors following local users
+CREATE TABLE remote_followers (
+    remote_actor_id UUID NOT NULL REFERENCES remote_actors(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    activity_id TEXT NOT NULL,
+    PRIMARY KEY (remote_actor_id, user_id)
+);
+
+CREATE INDEX remote_followers_user_id_idx ON remote_followers (user_id);
+
+-- Local users following remote actors, accepted once the remote server says so
+CREATE TABLE remote_following (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    remote_actor_id UUID NOT NULL REFERENCES remote_actors(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    activity_id TEXT NOT NULL UNIQUE,
+    accepted_at TIMESTAMP,
+    PRIMARY KEY (user_id, remote_actor_id)
+);
+
+CREATE INDEX remote_following_remote_actor_id_idx ON remote_following (remote_actor_id);
+
+-- Notes from remote actors someone here follows
+CREATE TABLE remote_notes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    remote_actor_id UUID NOT NULL REFERENCES remote_actors(id) ON DELETE CASCADE,
+    object_id TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    content TEXT NOT NULL,
+    published_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX remote_notes_remote_actor_id_idx ON remote_notes (remote_actor_id, published_at DESC);
+
+CREATE TABLE remote_likes (
+    remote_actor_id UUID NOT NULL REFERENCES remote_actors(id) ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    activity_id TEXT NOT NULL,
+    PRIMARY KEY (remote_actor_id, chirp_id)
+);
+
+CREATE INDEX remote_likes_chirp_id_idx ON remote_likes (chirp_id);
+
+-- Outgoing activities, one row per inbox, sent by the activity_deliveries job
+CREATE TABLE activity_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    inbox_url TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT,
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX activity_deliveries_pending_idx ON activity_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE activity_deliveries;
+DROP TABLE remote_likes;
+DROP TABLE remote_notes;
+DROP TABLE remote_following;
+DROP TABLE remote_followers;
+DROP TABLE remote_actors;
+DROP TABLE actor_keys;
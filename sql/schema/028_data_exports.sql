@@ -0,0 +1,23 @@
+-- +goose Up
+-- Account archives built by the data_exports job. The ZIP is kept in the
+-- database until it expires so any instance can serve the download.
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    archive BYTEA,
+    size_bytes BIGINT,
+    last_error TEXT,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id, created_at);
+CREATE INDEX data_exports_pending_idx ON data_exports (next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE data_exports;
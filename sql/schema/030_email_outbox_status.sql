@@ -0,0 +1,18 @@
+-- +goose Up
+-- Emails that ran out of attempts are marked failed instead of staying
+-- pending forever
+ALTER TABLE email_outbox
+ADD COLUMN status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed'));
+
+UPDATE email_outbox SET status = 'sent' WHERE sent_at IS NOT NULL;
+UPDATE email_outbox SET status = 'failed' WHERE sent_at IS NULL AND attempts >= 8;
+
+DROP INDEX email_outbox_pending_idx;
+CREATE INDEX email_outbox_pending_idx ON email_outbox (next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP INDEX email_outbox_pending_idx;
+CREATE INDEX email_outbox_pending_idx ON email_outbox (next_attempt_at) WHERE sent_at IS NULL;
+
+ALTER TABLE email_outbox
+DROP COLUMN status;
@@ -0,0 +1,54 @@
+-- +goose Up
+-- Being mentioned by a private account no longer reveals the chirp on its
+-- own; the mentioned user must also be an approved follower
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT author_id = viewer_id
+        OR (visibility = 'public' AND NOT EXISTS (
+            SELECT 1 FROM users u
+            WHERE u.id = author_id AND u.is_private
+        ))
+        OR (EXISTS (
+            SELECT 1 FROM chirp_mentions m
+            WHERE m.chirp_id = chirp_visible_to.chirp_id AND m.user_id = viewer_id
+        ) AND (
+            NOT EXISTS (
+                SELECT 1 FROM users u
+                WHERE u.id = author_id AND u.is_private
+            )
+            OR EXISTS (
+                SELECT 1 FROM follows f
+                WHERE f.follower_id = viewer_id AND f.followee_id = author_id
+            )
+        ))
+        OR (visibility IN ('public', 'followers') AND EXISTS (
+            SELECT 1 FROM follows f
+            WHERE f.follower_id = viewer_id AND f.followee_id = author_id
+        ));
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT author_id = viewer_id
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions m
+            WHERE m.chirp_id = chirp_visible_to.chirp_id AND m.user_id = viewer_id
+        )
+        OR (visibility = 'public' AND NOT EXISTS (
+            SELECT 1 FROM users u
+            WHERE u.id = author_id AND u.is_private
+        ))
+        OR (visibility IN ('public', 'followers') AND EXISTS (
+            SELECT 1 FROM follows f
+            WHERE f.follower_id = viewer_id AND f.followee_id = author_id
+        ));
+$$;
+-- +goose StatementEnd
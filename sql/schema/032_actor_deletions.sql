@@ -0,0 +1,11 @@
+-- +goose Up
+-- Accounts whose deletion has been federated. The user row (and the actor
+-- key that signs the Delete) is kept until the deliveries are done, and
+-- the deletion can no longer be cancelled.
+CREATE TABLE actor_deletions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE actor_deletions;
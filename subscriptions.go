@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+)
+
+// Subscription statuses. A user keeps Chirpy Red while their subscription is
+// active, past due or canceled, until the paid period ends.
+const (
+	subscriptionActive   = "active"
+	subscriptionPastDue  = "past_due"
+	subscriptionCanceled = "canceled"
+	subscriptionRefunded = "refunded"
+	subscriptionExpired  = "expired"
+)
+
+// defaultSubscriptionPeriod is used when Polka doesn't send period dates
+const defaultSubscriptionPeriod = 30 * 24 * time.Hour
+
+// applySubscriptionEvent updates the user's subscription and Chirpy Red flag
+// for a Polka event. Unknown events are ignored. It returns sql.ErrNoRows if
+// the user (or, for status changes, their subscription) doesn't exist.
+func applySubscriptionEvent(ctx context.Context, q *database.Queries, event polkaEvent, now time.Time) error {
+	var (
+		sub database.Subscription
+		err error
+	)
+
+	switch event.Event {
+	case "user.upgraded", "user.renewed":
+		start, end, err := subscriptionPeriod(ctx, q, event, now)
+		if err != nil {
+			return err
+		}
+		if _, err := q.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{
+			ID:          event.Data.UserID,
+			IsChirpyRed: true,
+		}); err != nil {
+			return err
+		}
+		sub, err = q.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+			UserID:             event.Data.UserID,
+			Status:             subscriptionActive,
+			CurrentPeriodStart: start,
+			CurrentPeriodEnd:   end,
+		})
+		if err != nil {
+			return err
+		}
+
+	case "user.payment_failed":
+		sub, err = q.UpdateSubscriptionStatus(ctx, database.UpdateSubscriptionStatusParams{
+			UserID: event.Data.UserID,
+			Status: subscriptionPastDue,
+		})
+		if err != nil {
+			return err
+		}
+
+	case "user.downgraded":
+		// Red stays on until the period they paid for runs out
+		sub, err = q.UpdateSubscriptionStatus(ctx, database.UpdateSubscriptionStatusParams{
+			UserID: event.Data.UserID,
+			Status: subscriptionCanceled,
+		})
+		if err != nil {
+			return err
+		}
+
+	case "user.refunded":
+		sub, err = q.UpdateSubscriptionStatus(ctx, database.UpdateSubscriptionStatusParams{
+			UserID: event.Data.UserID,
+			Status: subscriptionRefunded,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := q.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{
+			ID:          event.Data.UserID,
+			IsChirpyRed: false,
+		}); err != nil {
+			return err
+		}
+
+	default:
+		return nil
+	}
+
+	return q.CreateSubscriptionEvent(ctx, database.CreateSubscriptionEventParams{
+		SubscriptionID: sub.ID,
+		Event:          event.Event,
+		Status:         sub.Status,
+		PeriodEnd:      sub.CurrentPeriodEnd,
+	})
+}
+
+// subscriptionPeriod returns the billing period for an upgrade or renewal.
+// Polka's dates win; otherwise a renewal extends the current period and an
+// upgrade starts a new one now.
+func subscriptionPeriod(ctx context.Context, q *database.Queries, event polkaEvent, now time.Time) (time.Time, time.Time, error) {
+	start := now
+	if event.Data.PeriodStart != nil {
+		start = event.Data.PeriodStart.UTC()
+	} else if event.Event == "user.renewed" {
+		sub, err := q.GetSubscriptionByUser(ctx, event.Data.UserID)
+		if err != nil && err != sql.ErrNoRows {
+			return time.Time{}, time.Time{}, err
+		}
+		if err == nil && sub.CurrentPeriodEnd.After(now) {
+			start = sub.CurrentPeriodEnd
+		}
+	}
+
+	end := start.Add(defaultSubscriptionPeriod)
+	if event.Data.PeriodEnd != nil {
+		end = event.Data.PeriodEnd.UTC()
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("period end %s is not after start %s", end, start)
+	}
+	return start, end, nil
+}
+
+// expireLapsedSubscriptions takes Chirpy Red away from users whose paid
+// period has ended
+func (cfg *apiConfig) expireLapsedSubscriptions(ctx context.Context) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	subs, err := qtx.ExpireLapsedSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if _, err := qtx.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{
+			ID:          sub.UserID,
+			IsChirpyRed: false,
+		}); err != nil {
+			return err
+		}
+		err = qtx.CreateSubscriptionEvent(ctx, database.CreateSubscriptionEventParams{
+			SubscriptionID: sub.ID,
+			Event:          "expired",
+			Status:         sub.Status,
+			PeriodEnd:      sub.CurrentPeriodEnd,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
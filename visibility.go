@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"workspace/github.com/kozykoding/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Who can read a chirp. The rules themselves live in the chirp_visible_to SQL
+// function, which every query that reads chirps for a viewer filters on.
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityMentioned = "mentioned"
+)
+
+var validVisibilities = []string{
+	visibilityPublic,
+	visibilityFollowers,
+	visibilityMentioned,
+}
+
+const maxMentionsPerChirp = 10
+
+// chirpAudience is the part of a chirp or draft body that says who can read
+// it. Mentioned users can always read the chirp, whatever its visibility,
+// unless the author is private and they aren't an approved follower.
+type chirpAudience struct {
+	Visibility string      `json:"visibility"`
+	Mentions   []uuid.UUID `json:"mentions"`
+}
+
+// validate defaults the visibility to public and drops duplicate mentions
+// and mentions of the author
+func (a *chirpAudience) validate(authorID uuid.UUID) error {
+	if a.Visibility == "" {
+		a.Visibility = visibilityPublic
+	}
+	if !slices.Contains(validVisibilities, a.Visibility) {
+		return fmt.Errorf("visibility must be one of %v", validVisibilities)
+	}
+
+	mentions := []uuid.UUID{}
+	for _, id := range a.Mentions {
+		if id == authorID || slices.Contains(mentions, id) {
+			continue
+		}
+		mentions = append(mentions, id)
+	}
+	if len(mentions) > maxMentionsPerChirp {
+		return fmt.Errorf("A chirp can mention at most %d users", maxMentionsPerChirp)
+	}
+	a.Mentions = mentions
+	return nil
+}
+
+// createChirp stores a chirp along with who it mentions and the links and
+// hashtags in its body, and queues it for the author's remote followers.
+// Mentions of users that don't exist (or deleted their account before a
+// draft was published) are skipped.
+func (cfg *apiConfig) createChirp(ctx context.Context, q *database.Queries, authorID uuid.UUID, body string, audience chirpAudience) (database.Chirp, error) {
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		Body:       body,
+		UserID:     authorID,
+		Visibility: audience.Visibility,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if len(audience.Mentions) > 0 {
+		err := q.CreateChirpMentions(ctx, database.CreateChirpMentionsParams{
+			ChirpID: chirp.ID,
+			UserIds: audience.Mentions,
+		})
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	if err := saveChirpLinks(ctx, q, chirp.ID, body); err != nil {
+		return database.Chirp{}, err
+	}
+	if err := saveChirpHashtags(ctx, q, chirp.ID, body); err != nil {
+		return database.Chirp{}, err
+	}
+	if err := cfg.federateChirp(ctx, q, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, nil
+}